@@ -0,0 +1,122 @@
+// Package agent implements mylogin-agent, a daemon in the style of
+// ssh-agent that holds the decrypted content of a login file and serves
+// Login lookups over a Unix socket, so that other processes don't need to
+// read the file.
+//
+// The protocol is a sequence of JSON requests and responses, one per line.
+// The server checks the peer credentials of each connection (SO_PEERCRED,
+// on Linux only) against its Rules. On other platforms the peer is assumed
+// to be the user running the server, so the access is controlled only by
+// the permissions of the socket, like ssh-agent, and rules for other users
+// are refused (see CheckRules).
+//
+// See cmd/mylogin-agent for the daemon.
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// SocketEnv is the environment variable holding the path of the socket of
+// the agent, like SSH_AUTH_SOCK for ssh-agent.
+const SocketEnv = "MYLOGIN_AUTH_SOCK"
+
+// Operations of the protocol
+const (
+	OpLogin  = "login"  // Merged Login of Request.Sections
+	OpList   = "list"   // Names of the sections allowed to the peer
+	OpLock   = "lock"   // Lock with Request.Passphrase (owner only)
+	OpUnlock = "unlock" // Unlock with Request.Passphrase (owner only)
+)
+
+// Request is a request to the agent.
+type Request struct {
+	Op         string   `json:"op"`
+	Sections   []string `json:"sections,omitempty"`
+	Passphrase string   `json:"passphrase,omitempty"`
+}
+
+// Response is the response of the agent.
+type Response struct {
+	Error    string         `json:"error,omitempty"`
+	Login    *mylogin.Login `json:"login,omitempty"`
+	Sections []string       `json:"sections,omitempty"`
+}
+
+// AnyUID matches any user in a Rule.
+const AnyUID = -1
+
+// Rule allows a user to read sections.
+type Rule struct {
+	UID int
+	// Pattern matches section names, with the syntax of
+	// mylogin.Sections.Select.
+	Pattern string
+}
+
+// ParseRule parses a rule "<uid>:<pattern>", where uid is a numeric user
+// id or "*" for any user.
+func ParseRule(s string) (Rule, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return Rule{}, fmt.Errorf("invalid rule %q: <uid>:<pattern> expected", s)
+	}
+	r := Rule{UID: AnyUID, Pattern: s[i+1:]}
+	if uid := s[:i]; uid != "*" {
+		var err error
+		if r.UID, err = strconv.Atoi(uid); err != nil || r.UID < 0 {
+			return Rule{}, fmt.Errorf("invalid rule %q: bad uid", s)
+		}
+	}
+	if _, err := (mylogin.Sections{}).Select(r.Pattern); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+func (r Rule) String() string {
+	if r.UID == AnyUID {
+		return "*:" + r.Pattern
+	}
+	return strconv.Itoa(r.UID) + ":" + r.Pattern
+}
+
+// allows reports if the rule allows uid to read section.
+func (r Rule) allows(uid int, section string) bool {
+	if r.UID != AnyUID && r.UID != uid {
+		return false
+	}
+	selected, _ := (mylogin.Sections{{Name: section}}).Select(r.Pattern)
+	return len(selected) == 1
+}
+
+// DefaultRules allow the current user to read all sections.
+func DefaultRules() []Rule {
+	return []Rule{{UID: os.Getuid(), Pattern: "*"}}
+}
+
+// CheckRules checks that the rules can be enforced: other users than the
+// current one can only be allowed where the user of a client can be
+// checked (SO_PEERCRED, Linux only).
+func CheckRules(rules []Rule) error {
+	if peerCredentials {
+		return nil
+	}
+	uid := os.Getuid()
+	for _, r := range rules {
+		if r.UID != uid {
+			return fmt.Errorf("rule %s: other users can't be checked without SO_PEERCRED (Linux only)", r)
+		}
+	}
+	return nil
+}
+
+// DefaultSocket returns the socket path from $MYLOGIN_AUTH_SOCK.
+func DefaultSocket() string {
+	return os.Getenv(SocketEnv)
+}
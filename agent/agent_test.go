@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// startServer starts a server on a temporary socket. Call the returned
+// function to stop it.
+func startServer(t *testing.T, rules []Rule, lifetime time.Duration) (*Server, string, func()) {
+	dir, err := ioutil.TempDir("", "mylogin-agent-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "agent")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	srv, err := NewServer(mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("me"), Password: stringPtr("secret")}},
+		{Name: "prod-ro", Login: mylogin.Login{User: stringPtr("ro"), Host: stringPtr("db")}},
+		{Name: "prod-rw", Login: mylogin.Login{User: stringPtr("rw"), Host: stringPtr("db")}},
+	}, rules, lifetime)
+	if err != nil {
+		l.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	srv.Logf = t.Logf
+	go srv.Serve(l)
+	return srv, socket, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func dial(t *testing.T, socket string) *Client {
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAgentLogin(t *testing.T) {
+	_, socket, stop := startServer(t, nil, 0)
+	defer stop()
+	c := dial(t, socket)
+	defer c.Close()
+
+	login, err := c.Login([]string{"client", "prod-ro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mylogin.Login{User: stringPtr("ro"), Password: stringPtr("secret"), Host: stringPtr("db")}
+	if !reflect.DeepEqual(*login, expected) {
+		t.Errorf("got %v, expected %v", login, &expected)
+	}
+
+	if login, err = c.Login([]string{"dev"}); err != nil || !login.IsEmpty() {
+		t.Errorf("dev: got %v, %v", login, err)
+	}
+
+	names, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"client", "prod-ro", "prod-rw"}) {
+		t.Errorf("List: got %q", names)
+	}
+
+	// ReadLogin uses $MYLOGIN_AUTH_SOCK
+	defer os.Setenv(SocketEnv, os.Getenv(SocketEnv))
+	os.Setenv(SocketEnv, socket)
+	if login, err = ReadLogin([]string{"prod-rw"}); err != nil || *login.User != "rw" {
+		t.Errorf("ReadLogin: got %v, %v", login, err)
+	}
+}
+
+func TestAgentRules(t *testing.T) {
+	_, socket, stop := startServer(t, []Rule{
+		{UID: os.Getuid(), Pattern: "prod-ro"},
+		{UID: os.Getuid() + 1, Pattern: "*"},
+	}, 0)
+	defer stop()
+	c := dial(t, socket)
+	defer c.Close()
+
+	if login, err := c.Login([]string{"prod-ro"}); err != nil || *login.User != "ro" {
+		t.Errorf("prod-ro: got %v, %v", login, err)
+	}
+	if _, err := c.Login([]string{"prod-ro", "prod-rw"}); err == nil {
+		t.Error("prod-rw: access should be denied")
+	}
+	if _, err := c.Login([]string{""}); err == nil {
+		t.Error("client: access should be denied")
+	}
+	if names, err := c.List(); err != nil || !reflect.DeepEqual(names, []string{"prod-ro"}) {
+		t.Errorf("List: got %q, %v", names, err)
+	}
+}
+
+func TestAgentLock(t *testing.T) {
+	defer func(d time.Duration) { unlockDelay = d }(unlockDelay)
+	unlockDelay = 0
+
+	srv, socket, stop := startServer(t, nil, 0)
+	defer stop()
+	c := dial(t, socket)
+	defer c.Close()
+
+	if err := c.Unlock("x"); err == nil {
+		t.Error("unlock of an unlocked agent should fail")
+	}
+	if err := c.Lock(""); err == nil {
+		t.Error("empty passphrase should be refused")
+	}
+	if err := c.Lock("pass"); err != nil {
+		t.Fatal(err)
+	}
+	// Another client is locked out too
+	c2 := dial(t, socket)
+	defer c2.Close()
+	if _, err := c2.Login([]string{"client"}); err == nil {
+		t.Error("login should fail when locked")
+	}
+	if err := c.Unlock("bad"); err == nil {
+		t.Error("unlock with a bad passphrase should fail")
+	}
+	if err := c.Unlock("pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the owner may lock
+	srv.mu.Lock()
+	srv.owner = os.Getuid() + 1
+	srv.mu.Unlock()
+	if err := c.Lock("pass"); err == nil {
+		t.Error("lock by another user should be denied")
+	}
+	if _, err := c.Login([]string{"client"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAgentLifetime(t *testing.T) {
+	srv, socket, stop := startServer(t, nil, 50*time.Millisecond)
+	defer stop()
+	c := dial(t, socket)
+	defer c.Close()
+
+	if _, err := c.Login([]string{"client"}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	// Forgotten without waiting for a request
+	srv.mu.Lock()
+	forgotten := srv.sections == nil
+	srv.mu.Unlock()
+	if !forgotten {
+		t.Error("sections should be forgotten after the lifetime")
+	}
+	if _, err := c.Login([]string{"client"}); err == nil {
+		t.Error("login should fail after the lifetime")
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	if err := CheckRules(DefaultRules()); err != nil {
+		t.Error(err)
+	}
+	err := CheckRules([]Rule{{UID: os.Getuid() + 1, Pattern: "*"}})
+	if peerCredentials != (err == nil) {
+		t.Errorf("other user: got %v", err)
+	}
+	if _, err = NewServer(nil, []Rule{{UID: AnyUID, Pattern: "*"}}, 0); peerCredentials != (err == nil) {
+		t.Errorf("NewServer: got %v", err)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, test := range []struct {
+		in       string
+		expected Rule
+	}{
+		{"1000:prod-*", Rule{1000, "prod-*"}},
+		{"*:/^dev/", Rule{AnyUID, "/^dev/"}},
+	} {
+		r, err := ParseRule(test.in)
+		if err != nil || r != test.expected {
+			t.Errorf("%q: got %v, %v", test.in, r, err)
+		}
+		if r.String() != test.in {
+			t.Errorf("%q: String: %q", test.in, r.String())
+		}
+	}
+	for _, in := range []string{"prod", "x:prod", "-2:prod", "1000:["} {
+		if _, err := ParseRule(in); err == nil {
+			t.Errorf("%q: error expected", in)
+		}
+	}
+}
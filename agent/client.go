@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// Client is a connection to an agent.
+type Client struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// Dial connects to the agent listening on the Unix socket at path socket,
+// or at $MYLOGIN_AUTH_SOCK if socket is empty.
+func Dial(socket string) (*Client, error) {
+	if socket == "" {
+		socket = DefaultSocket()
+		if socket == "" {
+			return nil, errors.New(SocketEnv + " is not set")
+		}
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, rd: bufio.NewReader(conn)}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) call(req *Request) (*Response, error) {
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return nil, err
+	}
+	line, err := c.rd.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err = json.Unmarshal(line, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New("agent: " + resp.Error)
+	}
+	return &resp, nil
+}
+
+// Login returns the merge of the sections (see mylogin.Sections.Merge).
+// The Login is empty if none of the sections exist.
+func (c *Client) Login(sectionNames []string) (*mylogin.Login, error) {
+	resp, err := c.call(&Request{Op: OpLogin, Sections: sectionNames})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Login == nil {
+		return &mylogin.Login{}, nil
+	}
+	return resp.Login, nil
+}
+
+// List returns the names of the sections that the client may read.
+func (c *Client) List() ([]string, error) {
+	resp, err := c.call(&Request{Op: OpList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Sections, nil
+}
+
+// Lock locks the agent: lookups fail until Unlock is called with the same
+// passphrase.
+func (c *Client) Lock(passphrase string) error {
+	_, err := c.call(&Request{Op: OpLock, Passphrase: passphrase})
+	return err
+}
+
+// Unlock unlocks the agent.
+func (c *Client) Unlock(passphrase string) error {
+	_, err := c.call(&Request{Op: OpUnlock, Passphrase: passphrase})
+	return err
+}
+
+// ReadLogin is a replacement for mylogin.ReadLogin that reads the login
+// from the agent at $MYLOGIN_AUTH_SOCK instead of from a file.
+func ReadLogin(sectionNames []string) (*mylogin.Login, error) {
+	c, err := Dial("")
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.Login(sectionNames)
+}
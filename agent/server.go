@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// unlockDelay slows down brute force attacks on the passphrase, like
+// ssh-agent does.
+var unlockDelay = time.Second
+
+// Server holds the sections of a login file and serves them to the
+// clients allowed by the rules.
+type Server struct {
+	rules []Rule
+	// owner is the only user allowed to lock and unlock.
+	owner int
+	// Logf, if set, logs the requests (but not the credentials).
+	Logf func(format string, args ...interface{})
+
+	mu         sync.Mutex
+	sections   mylogin.Sections
+	expiry     *time.Timer // nil if no lifetime
+	expired    bool
+	locked     bool
+	passphrase [sha256.Size]byte
+	listeners  map[net.Listener]struct{}
+}
+
+// NewServer returns a Server that holds the sections. If rules is empty,
+// DefaultRules apply. If lifetime is not zero, the sections are forgotten
+// after that duration, like with ssh-agent -t.
+//
+// An error is returned if the rules can't be enforced (see CheckRules).
+func NewServer(sections mylogin.Sections, rules []Rule, lifetime time.Duration) (*Server, error) {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	if err := CheckRules(rules); err != nil {
+		return nil, err
+	}
+	s := &Server{
+		rules:     rules,
+		owner:     os.Getuid(),
+		sections:  sections,
+		listeners: make(map[net.Listener]struct{}),
+	}
+	if lifetime > 0 {
+		s.expiry = time.AfterFunc(lifetime, s.expire)
+	}
+	return s, nil
+}
+
+// expire forgets the sections at the end of the lifetime, even if no
+// client connects.
+func (s *Server) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sections = nil
+	s.expired = true
+}
+
+// Serve accepts connections on l until l is closed or Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close closes the listeners and forgets the sections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+	s.sections = nil
+	var err error
+	for l := range s.listeners {
+		if e := l.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	uid, err := peerUID(conn)
+	if err != nil {
+		s.logf("peer credentials: %v", err)
+		return
+	}
+	rd := bufio.NewReader(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req Request
+		var resp *Response
+		if err = json.Unmarshal(line, &req); err != nil {
+			resp = &Response{Error: "invalid request: " + err.Error()}
+		} else {
+			resp = s.handle(uid, &req)
+			if resp.Error != "" {
+				s.logf("uid %d: %s %q: %s", uid, req.Op, req.Sections, resp.Error)
+			} else {
+				s.logf("uid %d: %s %q", uid, req.Op, req.Sections)
+			}
+		}
+		if err = enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+var (
+	errLocked        = errors.New("agent is locked")
+	errExpired       = errors.New("credentials have expired")
+	errBadPassphrase = errors.New("bad passphrase")
+)
+
+func (s *Server) handle(uid int, req *Request) *Response {
+	resp, err := s.do(uid, req)
+	if err == errBadPassphrase {
+		// Not under s.mu, to not stall the other clients
+		time.Sleep(unlockDelay)
+	}
+	if err != nil {
+		return &Response{Error: err.Error()}
+	}
+	return resp
+}
+
+func (s *Server) do(uid int, req *Request) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expired {
+		return nil, errExpired
+	}
+
+	switch req.Op {
+	case OpLock, OpUnlock:
+		// Other users may be allowed to read sections, but not to lock the
+		// owner out
+		if uid != s.owner {
+			return nil, fmt.Errorf("%s: access denied", req.Op)
+		}
+	}
+
+	switch req.Op {
+	case OpLock:
+		if s.locked {
+			return nil, errLocked
+		}
+		if req.Passphrase == "" {
+			return nil, errors.New("empty passphrase")
+		}
+		s.locked = true
+		s.passphrase = sha256.Sum256([]byte(req.Passphrase))
+		return &Response{}, nil
+	case OpUnlock:
+		if !s.locked {
+			return nil, errors.New("agent is not locked")
+		}
+		h := sha256.Sum256([]byte(req.Passphrase))
+		if subtle.ConstantTimeCompare(h[:], s.passphrase[:]) != 1 {
+			return nil, errBadPassphrase
+		}
+		s.locked = false
+		s.passphrase = [sha256.Size]byte{}
+		return &Response{}, nil
+	}
+
+	if s.locked {
+		return nil, errLocked
+	}
+
+	switch req.Op {
+	case OpList:
+		var names []string
+		seen := make(map[string]bool)
+		for _, sec := range s.sections {
+			if !seen[sec.Name] && s.allowed(uid, sec.Name) {
+				seen[sec.Name] = true
+				names = append(names, sec.Name)
+			}
+		}
+		return &Response{Sections: names}, nil
+	case OpLogin:
+		names := make([]string, len(req.Sections))
+		for i, name := range req.Sections {
+			if name == "" {
+				name = mylogin.DefaultSection
+			}
+			if !s.allowed(uid, name) {
+				return nil, fmt.Errorf("section %q: access denied", name)
+			}
+			names[i] = name
+		}
+		return &Response{Login: s.sections.Merge(names)}, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", req.Op)
+	}
+}
+
+func (s *Server) allowed(uid int, section string) bool {
+	for _, r := range s.rules {
+		if r.allows(uid, section) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+//go:build !windows
+// +build !windows
+
+package mylogin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// platformCheckFile checks f, opened from filename. The mode and owner are
+// checked on the opened file, so the result can't be fooled by a rename
+// after the check. See ReadOptions.Shared for the checks of a shared file.
+func platformCheckFile(filename string, f *os.File, shared bool) (errs []error) {
+	problem := func(format string, args ...interface{}) {
+		errs = append(errs, &PermissionError{Path: filename, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return []error{err}
+	}
+	if lfi, err := os.Lstat(filename); err == nil && lfi.Mode()&os.ModeSymlink != 0 {
+		target, err := filepath.EvalSymlinks(filename)
+		if err != nil {
+			return []error{err}
+		}
+		if !shared && !inHomeDir(target) {
+			problem("symbolic link to %s, outside of the home directory", target)
+		}
+		if tfi, err := os.Stat(target); err != nil || !os.SameFile(tfi, fi) {
+			problem("symbolic link changed while being checked")
+		}
+	}
+
+	if shared {
+		if perm := fi.Mode().Perm(); perm&0133 != 0 {
+			problem("mode %04o, should be writable only by its owner", perm)
+		}
+		return
+	}
+	// Same check as the MySQL client (mysys/my_default.cc)
+	if perm := fi.Mode().Perm(); perm&0177 != 0 {
+		problem("mode %04o, should be readable/writable only by its owner", perm)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		problem("owned by uid %d, not by the current user", st.Uid)
+	}
+	return
+}
+
+// inHomeDir reports if path (with symbolic links resolved) is inside the
+// home directory of the current user.
+func inHomeDir(path string) bool {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	if home, err = filepath.EvalSymlinks(home); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(home, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,184 @@
+//go:build !windows
+// +build !windows
+
+package mylogin_test
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestReadOptionsPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	home := filepath.Join(dir, "home")
+	if err = os.Mkdir(home, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen")}},
+	}
+	write := func(filename string, mode os.FileMode) {
+		t.Helper()
+		if err := mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filename, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink := func(target, link string) {
+		t.Helper()
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	safe := filepath.Join(home, "safe.cnf")
+	write(safe, 0600)
+	readable := filepath.Join(home, "readable.cnf")
+	write(readable, 0640)
+	outside := filepath.Join(dir, "outside.cnf")
+	write(outside, 0600)
+	linkInside := filepath.Join(home, "inside-link.cnf")
+	symlink(safe, linkInside)
+	linkOutside := filepath.Join(home, "outside-link.cnf")
+	symlink(outside, linkOutside)
+
+	for _, tc := range []struct {
+		filename string
+		problems int
+	}{
+		{safe, 0},
+		{linkInside, 0},
+		{readable, 1},
+		{linkOutside, 1},
+	} {
+		errs := mylogin.CheckPermissions(tc.filename)
+		if len(errs) != tc.problems {
+			t.Errorf("%s: got %v, expected %d problems", tc.filename, errs, tc.problems)
+		}
+
+		_, err := mylogin.ReadSections(tc.filename)
+		if tc.problems == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tc.filename, err)
+			}
+		} else if _, ok := err.(*mylogin.PermissionError); !ok {
+			t.Errorf("%s: got %v, expected a *PermissionError", tc.filename, err)
+		}
+
+		var warnings []error
+		opts := mylogin.ReadOptions{
+			Permissions: mylogin.PermissionWarn,
+			Warn:        func(err error) { warnings = append(warnings, err) },
+		}
+		if _, err = opts.ReadSections(tc.filename); err != nil {
+			t.Errorf("%s: %v", tc.filename, err)
+		}
+		if len(warnings) != tc.problems {
+			t.Errorf("%s: got warnings %v, expected %d", tc.filename, warnings, tc.problems)
+		}
+
+		opts = mylogin.ReadOptions{Permissions: mylogin.PermissionIgnore}
+		if _, err = opts.ReadFile(tc.filename); err != nil {
+			t.Errorf("%s: %v", tc.filename, err)
+		}
+
+		// Open applies the same policy
+		f, err := (&mylogin.ReadOptions{}).Open(tc.filename)
+		if (err == nil) != (tc.problems == 0) {
+			t.Errorf("%s: Open: got %v", tc.filename, err)
+		}
+		if f != nil {
+			f.Close()
+		}
+	}
+
+	if _, err = mylogin.ReadSections(filepath.Join(home, "missing.cnf")); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
+
+func TestReadOptionsShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := mylogin.Sections{
+		{Name: "reporting", Login: mylogin.Login{User: stringPtr("ro")}},
+	}
+	filename := filepath.Join(dir, "team.cnf")
+	if err = mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.cnf")
+	if err = os.Symlink(filename, link); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		mode     os.FileMode
+		problems int
+	}{
+		{0600, 0},
+		{0644, 0},
+		{0664, 1},
+		{0755, 1},
+	} {
+		if err = os.Chmod(filename, tc.mode); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{filename, link} {
+			var warnings []error
+			opts := mylogin.ReadOptions{
+				Permissions: mylogin.PermissionWarn,
+				Warn:        func(err error) { warnings = append(warnings, err) },
+				Shared:      true,
+			}
+			if _, err = opts.ReadSections(name); err != nil {
+				t.Errorf("%s %04o: %v", name, tc.mode, err)
+			}
+			if len(warnings) != tc.problems {
+				t.Errorf("%s %04o: got warnings %v, expected %d", name, tc.mode, warnings, tc.problems)
+			}
+		}
+	}
+}
+
+func TestPermissionPolicyText(t *testing.T) {
+	for _, p := range []mylogin.PermissionPolicy{mylogin.PermissionRefuse, mylogin.PermissionWarn, mylogin.PermissionIgnore} {
+		text, _ := p.MarshalText()
+		var got mylogin.PermissionPolicy
+		if err := got.UnmarshalText(text); err != nil || got != p {
+			t.Errorf("%s: got %v, %v", text, got, err)
+		}
+	}
+	var p mylogin.PermissionPolicy
+	if err := p.UnmarshalText([]byte("allow")); err == nil {
+		t.Error("error expected")
+	}
+}
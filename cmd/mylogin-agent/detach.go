@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "syscall"
+
+// detach returns the attributes of the process of the agent, detached from
+// the terminal.
+func detach() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
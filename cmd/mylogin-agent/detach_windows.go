@@ -0,0 +1,7 @@
+package main
+
+import "syscall"
+
+func detach() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,285 @@
+// Command mylogin-agent holds the content of ~/.mylogin.cnf in memory and
+// serves logins over a Unix socket, in the style of ssh-agent.
+//
+// # Usage
+//
+//	eval $(mylogin-agent [-file ~/.mylogin.cnf] [-a <socket>] [-t <lifetime>] [-allow <uid>:<pattern>] ... [-d])
+//	mylogin-agent list
+//	mylogin-agent lock
+//	mylogin-agent unlock
+//
+// The agent reads the login file once, then detaches and prints the shell
+// commands to export its socket as $MYLOGIN_AUTH_SOCK and its pid as
+// $MYLOGIN_AGENT_PID. With -d it stays in the foreground and logs the
+// requests to stderr.
+//
+// -t sets the lifetime of the credentials (such as "8h"): after that, the
+// agent forgets them and all lookups fail.
+//
+// -allow allows the user with the given numeric uid ("*" for any user) to
+// read the sections matching the pattern (see
+// [github.com/dolmen-go/mylogin.Sections.Select]). On Linux the user of each
+// client is checked with SO_PEERCRED. By default only the current user
+// has access to all sections. If other users are allowed, the socket is
+// made world-writable, so -a must give a path that they can reach (the
+// default directory is private). Other platforms can't check the user of a
+// client: only the current user may be allowed, and the access is
+// controlled by the permissions of the socket.
+//
+// list prints the sections that the agent would serve to the current user.
+// lock locks the agent with a passphrase: lookups fail until unlock is
+// called with the same passphrase. Only the user running the agent may
+// lock and unlock it.
+//
+// Programs read logins from the agent with package
+// [github.com/dolmen-go/mylogin/agent].
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/agent"
+	"github.com/dolmen-go/mylogin/prompt"
+)
+
+// listenerFDEnv passes the listening socket to the detached process.
+const listenerFDEnv = "MYLOGIN_AGENT_LISTENER"
+
+// rulesFlag is a repeatable flag.
+type rulesFlag []agent.Rule
+
+func (f *rulesFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *rulesFlag) Set(s string) error {
+	r, err := agent.ParseRule(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
+
+func main() {
+	var (
+		filename string
+		socket   string
+		lifetime time.Duration
+		rules    rulesFlag
+		debug    bool
+	)
+	flag.StringVar(&filename, "file", mylogin.DefaultFile(), "login `file`")
+	flag.StringVar(&socket, "a", "", "bind the agent to the Unix domain `socket`")
+	flag.DurationVar(&lifetime, "t", 0, "`lifetime` of the credentials (default: unlimited)")
+	flag.Var(&rules, "allow", "allow `uid:pattern` (repeatable)")
+	flag.BoolVar(&debug, "d", false, "debug mode: stay in the foreground")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options]\n       %s list|lock|unlock\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var err error
+	switch flag.Arg(0) {
+	case "":
+		err = run(filename, socket, lifetime, rules, debug)
+	case "list", "lock", "unlock":
+		if flag.NArg() > 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = control(socket, flag.Arg(0))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(filename, socket string, lifetime time.Duration, rules []agent.Rule, debug bool) error {
+	// Checked before detaching
+	if err := agent.CheckRules(rules); err != nil {
+		return err
+	}
+	sections, err := mylogin.ReadSections(filename)
+	if err != nil {
+		return err
+	}
+
+	if fd := os.Getenv(listenerFDEnv); fd != "" {
+		// Detached process
+		n, err := strconv.Atoi(fd)
+		if err != nil {
+			return err
+		}
+		l, err := net.FileListener(os.NewFile(uintptr(n), "listener"))
+		if err != nil {
+			return err
+		}
+		return serve(l, socket, sections, rules, lifetime, nil)
+	}
+
+	var dir string
+	if socket == "" {
+		// Like ssh-agent, in a private directory
+		if dir, err = ioutil.TempDir("", "mylogin-"); err != nil {
+			return err
+		}
+		socket = filepath.Join(dir, "agent."+strconv.Itoa(os.Getpid()))
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		if dir != "" {
+			os.Remove(dir)
+		}
+		return err
+	}
+	mode := os.FileMode(0600)
+	if othersAllowed(rules) {
+		// Access is checked with the peer credentials
+		mode = 0666
+	}
+	if err = os.Chmod(socket, mode); err != nil {
+		l.Close()
+		return err
+	}
+
+	if debug {
+		printEnv(socket, os.Getpid())
+		return serve(l, socket, sections, rules, lifetime, log.New(os.Stderr, "", log.LstdFlags))
+	}
+
+	f, err := l.(*net.UnixListener).File()
+	if err != nil {
+		return err
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, "-file", filename, "-a", socket, "-t", lifetime.String())
+	for _, r := range rules {
+		cmd.Args = append(cmd.Args, "-allow", r.String())
+	}
+	cmd.Env = append(os.Environ(), listenerFDEnv+"=3")
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.SysProcAttr = detach() // see detach.go, detach_windows.go
+	if err = cmd.Start(); err != nil {
+		os.Remove(socket)
+		return err
+	}
+	f.Close()
+	printEnv(socket, cmd.Process.Pid)
+	return nil
+}
+
+// othersAllowed reports if the rules allow other users than the current
+// one.
+func othersAllowed(rules []agent.Rule) bool {
+	uid := os.Getuid()
+	for _, r := range rules {
+		if r.UID != uid {
+			return true
+		}
+	}
+	return false
+}
+
+func printEnv(socket string, pid int) {
+	fmt.Printf("%s=%s; export %[1]s;\n", agent.SocketEnv, socket)
+	fmt.Printf("MYLOGIN_AGENT_PID=%d; export MYLOGIN_AGENT_PID;\n", pid)
+	fmt.Printf("echo Agent pid %d;\n", pid)
+}
+
+func serve(l net.Listener, socket string, sections mylogin.Sections, rules []agent.Rule, lifetime time.Duration, logger *log.Logger) error {
+	srv, err := agent.NewServer(sections, rules, lifetime)
+	if err != nil {
+		l.Close()
+		os.Remove(socket)
+		return err
+	}
+	if logger != nil {
+		srv.Logf = logger.Printf
+	}
+
+	stopped := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		close(stopped)
+		srv.Close()
+	}()
+
+	err = srv.Serve(l)
+	os.Remove(socket)
+	if dir := filepath.Dir(socket); filepath.Dir(dir) == filepath.Clean(os.TempDir()) && strings.HasPrefix(filepath.Base(dir), "mylogin-") {
+		// The private directory created by run
+		os.Remove(dir)
+	}
+	select {
+	case <-stopped:
+		return nil
+	default:
+	}
+	return err
+}
+
+func control(socket, command string) error {
+	c, err := agent.Dial(socket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	switch command {
+	case "list":
+		names, err := c.List()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	case "lock":
+		passphrase, err := prompt.Password("Enter lock password: ")
+		if err != nil {
+			return err
+		}
+		again, err := prompt.Password("Again: ")
+		if err != nil {
+			return err
+		}
+		if again != passphrase {
+			return fmt.Errorf("passwords do not match")
+		}
+		return c.Lock(passphrase)
+	default: // unlock
+		passphrase, err := prompt.Password("Enter lock password: ")
+		if err != nil {
+			return err
+		}
+		return c.Unlock(passphrase)
+	}
+}
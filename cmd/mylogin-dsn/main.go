@@ -8,20 +8,50 @@ import (
 	"github.com/dolmen-go/mylogin"
 )
 
+// filesFlag is a repeatable flag.
+type filesFlag []string
+
+func (f *filesFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *filesFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
 func main() {
 	var database string
+	var shared filesFlag
 	flag.StringVar(&database, "database", "", "database name")
+	flag.Var(&shared, "shared", "shared login `file` read before "+mylogin.DefaultFile()+", skipped if missing (repeatable)")
 	flag.Parse()
 
-	var sections []string
-	if len(os.Args) <= 1 {
+	sections := flag.Args()
+	if len(sections) == 0 {
 		sections = []string{mylogin.DefaultSection}
-	} else {
-		sections = os.Args[1:]
 	}
-	login, err := mylogin.ReadLogin(mylogin.DefaultFile(), sections)
+
+	// A shared file is owned by another user or readable by the group:
+	// only the problems that remain with relaxed checks are reported
+	sharedOpts := mylogin.ReadOptions{
+		Permissions: mylogin.PermissionWarn,
+		Warn: func(err error) {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		},
+		Shared: true,
+	}
+	var layers mylogin.Layers
+	for _, f := range shared {
+		layers = append(layers, sharedOpts.FileLayer(f, true))
+	}
+	// With shared files, the personal file is optional
+	layers = append(layers, mylogin.FileLayer(mylogin.DefaultFile(), len(shared) > 0))
+
+	login, err := layers.ReadLogin(sections)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	fmt.Println(login.DSN() + database)
@@ -1,15 +1,21 @@
 // This program dumps the key of mylogin.cnf files
 // (for exploring the key space)
+//
+// Keys are printed in the compact form (hex and base64) accepted by
+// mylogin.Key.UnmarshalText and by "mylogin encrypt -key".
+//
+// Like the MySQL client, files with unsafe permissions are refused, unless
+// -permissions=warn or -permissions=ignore is given.
 
 package main
 
 import (
 	"bufio"
 	"encoding/base64"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/dolmen-go/mylogin"
 )
@@ -20,56 +26,56 @@ func printKey(key mylogin.Key) {
 	// to avoid printable characters.
 	//
 	// Let's validate our assumption...
-	for _, b := range key {
-		if b >= 32 {
-			fmt.Printf("%X\n", key)
-			return
-		}
+	if key.Validate() != nil {
+		fmt.Printf("%X\n", key)
+		return
 	}
 
-	var compactKey [(len(key)*5 + 7) / 8]byte
-	var j int
-	var acc uint // accumultator
-	var accBits uint
-	for _, b := range key {
-		acc = (acc << 5) | uint(b)
-		accBits += 5
-		for accBits >= 8 {
-			accBits -= 8
-			compactKey[j] = byte(acc >> accBits)
-			j++
-		}
-	}
-	// Only 4 bits remain
-	compactKey[j] = byte(acc << (8 - accBits))
-
-	//fmt.Printf("%X\n", key)
+	// Compact form: 25 hex digits (see mylogin.Key.MarshalText)
+	text, _ := key.MarshalText()
 
 	// Encode to base64 but remove the last char
-	b64 := base64.RawURLEncoding.EncodeToString(compactKey[:])[:(len(key)*5+5)/6]
+	compactKey, _ := hex.DecodeString(string(text) + "0")
+	b64 := base64.RawURLEncoding.EncodeToString(compactKey)[:(len(key)*5+5)/6]
+
+	fmt.Printf("%s %s\n", text, b64)
+}
 
-	// Print only the high nibble of the last byte
-	const hex = "0123456789ABCDEF"
-	fmt.Printf("%X%c %s\n",
-		compactKey[:len(compactKey)-1], hex[compactKey[len(compactKey)-1]>>4],
-		b64)
+// policyFlag is the -permissions flag.
+type policyFlag struct {
+	p *mylogin.PermissionPolicy
+}
+
+func (f policyFlag) String() string {
+	if f.p == nil {
+		return ""
+	}
+	return f.p.String()
+}
+
+func (f policyFlag) Set(s string) error {
+	return f.p.UnmarshalText([]byte(s))
 }
 
 func main() {
+	opts := mylogin.ReadOptions{
+		Warn: func(err error) {
+			log.Print("warning: ", err)
+		},
+	}
+	flag.Var(policyFlag{&opts.Permissions}, "permissions", "`policy` for a file with unsafe permissions: refuse, warn or ignore")
 	flag.Parse()
 
-	var filenames []string
-	if len(os.Args) > 1 {
-		filenames = os.Args[1:]
-	} else {
+	filenames := flag.Args()
+	if len(filenames) == 0 {
 		filenames = []string{mylogin.DefaultFile()}
 	}
 
 	for _, filename := range filenames {
 		func(filename string) {
-			f, err := os.Open(filename)
+			f, err := opts.Open(filename)
 			if err != nil {
-				log.Fatal(filename, ": ", err)
+				log.Fatal(err)
 			}
 			defer f.Close()
 
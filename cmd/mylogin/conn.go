@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/mysqltls"
+	"github.com/go-sql-driver/mysql"
+)
+
+// connOptions are the options of the connections to the server, for the
+// commands that connect (ping, rotate).
+type connOptions struct {
+	Timeout   time.Duration
+	Transport mylogin.TransportOptions
+	// TLS is the name of a TLS configuration registered with the driver
+	// (see mysqltls.Register), or empty.
+	TLS string
+
+	defaultsFile string
+}
+
+// registerFlags registers -timeout, -protocol and -defaults-file.
+func (opts *connOptions) registerFlags(flags *flag.FlagSet, timeout time.Duration) {
+	flags.DurationVar(&opts.Timeout, "timeout", timeout, "timeout of each connection")
+	flags.StringVar(&opts.Transport.Protocol, "protocol", "", "transport: tcp or socket (default: like the mysql client)")
+	flags.StringVar(&opts.defaultsFile, "defaults-file", "", "option `file` with the TLS options (ssl-mode, ssl-ca...) in [client]")
+}
+
+// registerTLS registers the TLS options of -defaults-file, if set, with the
+// driver under name.
+func (opts *connOptions) registerTLS(name string) error {
+	if opts.defaultsFile == "" {
+		return nil
+	}
+	tlsOpts, err := mylogin.ReadTLSOptions(opts.defaultsFile, []string{mylogin.DefaultSection})
+	if err != nil {
+		return err
+	}
+	opts.TLS, err = mysqltls.Register(name, tlsOpts)
+	return err
+}
+
+// open returns a database handle for login, with the transport resolved
+// like the mysql client (see mylogin.Login.ResolveTransport).
+func (opts *connOptions) open(login *mylogin.Login) (*sql.DB, error) {
+	login, err := login.ResolveTransport(&opts.Transport)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := mysql.ParseDSN(login.DSN())
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = opts.Timeout
+	if opts.TLS != "" {
+		cfg.TLSConfig = opts.TLS
+	}
+	return sql.Open("mysql", cfg.FormatDSN())
+}
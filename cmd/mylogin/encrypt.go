@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// keyFlag is a flag.Value for a mylogin.Key (see mylogin.Key.UnmarshalText).
+type keyFlag struct {
+	key *mylogin.Key
+}
+
+func (f keyFlag) String() string {
+	if f.key == nil || f.key.IsZero() {
+		return ""
+	}
+	text, _ := f.key.MarshalText()
+	return string(text)
+}
+
+func (f keyFlag) Set(s string) error {
+	return f.key.UnmarshalText([]byte(s))
+}
+
+// cmdEncrypt encrypts a plaintext login file.
+func cmdEncrypt(args []string) error {
+	var key mylogin.Key
+	flags := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	flags.Var(keyFlag{&key}, "key", "encryption `key` in the compact form printed by mylogin-key (default: a new random key)")
+	bigEndian := flags.Bool("big-endian", false, "write chunk sizes as big endian")
+	output := flags.String("o", "", "output `file` (default: stdout)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin encrypt [-key <key>] [-big-endian] [-o <file>] [<plaintext file>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var plainText []byte
+	var err error
+	switch flags.NArg() {
+	case 0:
+		plainText, err = ioutil.ReadAll(os.Stdin)
+	case 1:
+		plainText, err = ioutil.ReadFile(flags.Arg(0))
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Check the syntax
+	if _, err = mylogin.Parse(bytes.NewReader(plainText)); err != nil {
+		return err
+	}
+
+	if key.IsZero() {
+		if key, err = mylogin.NewKey(rand.Read); err != nil {
+			return err
+		}
+	}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if *bigEndian {
+		byteOrder = binary.BigEndian
+	}
+	// The plaintext is encrypted as is
+	f := &plainFile{key: key, byteOrder: byteOrder, plainText: plainText}
+
+	if *output != "" {
+		return mylogin.WriteFile(*output, f)
+	}
+	return mylogin.Encode(os.Stdout, f)
+}
+
+// plainFile is a mylogin.File.
+type plainFile struct {
+	key       mylogin.Key
+	byteOrder binary.ByteOrder
+	plainText []byte
+}
+
+func (f *plainFile) Key() mylogin.Key {
+	return f.key
+}
+
+func (f *plainFile) ByteOrder() binary.ByteOrder {
+	return f.byteOrder
+}
+
+func (f *plainFile) PlainText() io.Reader {
+	return bytes.NewReader(f.plainText)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdEnv prints the merged login of the given sections as environment
+// variables used by the MySQL client tools.
+func cmdEnv(args []string) error {
+	var shellNames []string
+	for name := range shells {
+		shellNames = append(shellNames, name)
+	}
+	sort.Strings(shellNames)
+
+	flags := flag.NewFlagSet("env", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	shell := flags.String("shell", "bash", "output syntax: "+strings.Join(shellNames, ", "))
+	prefix := flags.String("prefix", "MYSQL_", "variables prefix: letters, digits and '_'")
+	withPassword := flags.Bool("password", false, "also export the password as <prefix>PWD (insecure: visible to other users on some systems)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin env [-file <mylogin.cnf>] [-shell <shell>] [-prefix <prefix>] [-password] [<section> ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	assign, ok := shells[*shell]
+	if !ok {
+		return fmt.Errorf("unknown shell %q", *shell)
+	}
+	// The output is meant to be evaluated by a shell
+	if err := checkPrefix(*prefix); err != nil {
+		return err
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{mylogin.DefaultSection}
+	}
+
+	login, err := readOpts.ReadLogin(*filename, names)
+	if err != nil {
+		return err
+	}
+	if login == nil {
+		return errors.New("sections don't exist or are empty")
+	}
+
+	for _, v := range []struct {
+		name  string
+		value *string
+	}{
+		{"HOST", login.Host},
+		{"TCP_PORT", login.Port},
+		{"UNIX_PORT", login.Socket},
+		{"USER", login.User},
+	} {
+		if v.value != nil {
+			fmt.Println(assign(*prefix+v.name, *v.value))
+		}
+	}
+	if *withPassword && login.Password != nil {
+		fmt.Println(assign(*prefix+"PWD", *login.Password))
+	}
+	return nil
+}
+
+// validPrefix matches the prefixes that give valid variable names in all
+// the shells.
+var validPrefix = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// checkPrefix checks the prefix of variable names.
+func checkPrefix(prefix string) error {
+	if !validPrefix.MatchString(prefix) {
+		return fmt.Errorf("invalid prefix %q: [A-Za-z_][A-Za-z0-9_]* expected", prefix)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdExec runs a command with an isolated login file that holds only the
+// requested sections.
+func cmdExec(args []string) error {
+	flags := flag.NewFlagSet("exec", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	keepNames := flags.Bool("keep-names", false, "keep sections under their name instead of merging them under ["+mylogin.DefaultSection+"]")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin exec [-file <mylogin.cnf>] [-keep-names] <section> ... -- <command> [<arg> ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	names, argv := flags.Args(), []string(nil)
+	for i, arg := range names {
+		if arg == "--" {
+			names, argv = names[:i], names[i+1:]
+			break
+		}
+	}
+	if argv == nil && len(names) > 1 {
+		// No "--": the first argument is the section
+		names, argv = names[:1], names[1:]
+	}
+	if len(names) == 0 || len(argv) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	sections, err := readOpts.ReadSections(*filename)
+	if err != nil {
+		return err
+	}
+
+	var selected mylogin.Sections
+	if *keepNames {
+		for _, name := range names {
+			login := sections.Login(name)
+			if login == nil {
+				return fmt.Errorf("%s: section doesn't exist", name)
+			}
+			selected = append(selected, mylogin.Section{Name: name, Login: *login})
+		}
+	} else {
+		login := sections.Merge(names)
+		if login == nil {
+			return errors.New("sections don't exist or are empty")
+		}
+		selected = mylogin.Sections{{Name: mylogin.DefaultSection, Login: *login}}
+	}
+
+	code, err := runWithSections(selected, argv)
+	if err != nil {
+		return err
+	}
+	os.Exit(code)
+	return nil
+}
+
+// runWithSections runs a command (see run) with MYSQL_TEST_LOGIN_FILE set to
+// a temporary login file that holds sections, and removes the file when the
+// command exits. It returns the exit code of the command.
+func runWithSections(sections mylogin.Sections, argv []string) (int, error) {
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		return 0, err
+	}
+
+	// ioutil.TempFile creates the file with mode 0600, as mysql_config_editor
+	tmp, err := ioutil.TempFile("", "mylogin-*.cnf")
+	if err != nil {
+		return 0, err
+	}
+	tmpName := tmp.Name()
+	err = mylogin.Encode(tmp, mylogin.NewFile(key, nil, sections))
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return 0, err
+	}
+
+	code, err := run(argv, "MYSQL_TEST_LOGIN_FILE="+tmpName)
+	if errRemove := os.Remove(tmpName); err == nil {
+		err = errRemove
+	}
+	return code, err
+}
+
+// run runs a command with an additional environment variable, forwarding
+// signals, and returns its exit code.
+func run(argv []string, envVar string) (int, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	varName := envVar[:strings.IndexByte(envVar, '=')+1]
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, varName) {
+			cmd.Env = append(cmd.Env, v)
+		}
+	}
+	cmd.Env = append(cmd.Env, envVar)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
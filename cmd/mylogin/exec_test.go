@@ -0,0 +1,90 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestRunWithSections(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("me"), Password: stringPtr("secret")}},
+	}
+	pathFile := filepath.Join(dir, "path")
+	copyFile := filepath.Join(dir, "copy.cnf")
+
+	// The child sees the login file, and its exit code is passed through
+	code, err := runWithSections(sections, []string{"sh", "-c", `echo "$MYSQL_TEST_LOGIN_FILE" > "$1"; cp "$MYSQL_TEST_LOGIN_FILE" "$2"; exit 3`, "sh", pathFile, copyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("exit code: got %d, expected 3", code)
+	}
+	path, err := ioutil.ReadFile(pathFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpName := strings.TrimSpace(string(path))
+	if tmpName == "" {
+		t.Fatal("MYSQL_TEST_LOGIN_FILE not set")
+	}
+	if _, err = os.Stat(tmpName); !os.IsNotExist(err) {
+		t.Errorf("%s: not removed (%v)", tmpName, err)
+	}
+	got, err := mylogin.ReadSections(copyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sections) {
+		t.Errorf("got %#v, expected %#v", got, sections)
+	}
+
+	// A child killed by a signal gives 128 + the signal number, like shells
+	code, err = runWithSections(sections, []string{"sh", "-c", "kill -TERM $$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 128+int(syscall.SIGTERM) {
+		t.Errorf("signal: got %d, expected %d", code, 128+int(syscall.SIGTERM))
+	}
+
+	// A signal received by mylogin is forwarded to the child
+	ready := filepath.Join(dir, "ready")
+	go func() {
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(ready); err == nil {
+				syscall.Kill(os.Getpid(), syscall.SIGHUP)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	code, err = runWithSections(sections, []string{"sh", "-c", `touch "$1"; exec sleep 10`, "sh", ready})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 128+int(syscall.SIGHUP) {
+		t.Errorf("forwarded signal: got %d, expected %d", code, 128+int(syscall.SIGHUP))
+	}
+
+	if _, err = runWithSections(sections, []string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("missing command: error expected")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdExplain prints where each option of the merged login comes from.
+func cmdExplain(args []string) error {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin explain [-file <mylogin.cnf>] [<login-path> ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Like the MySQL client, [client] is read before the login paths
+	names := []string{mylogin.DefaultSection}
+	for _, name := range flags.Args() {
+		if name != mylogin.DefaultSection {
+			names = append(names, name)
+		}
+	}
+
+	src, err := readOpts.ReadSource(*filename)
+	if err != nil {
+		return err
+	}
+	login, traces := mylogin.MergeTrace(names, src)
+	if login == nil {
+		return errors.New("sections don't exist or are empty")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, t := range traces {
+		var overridden string
+		if t.Overridden {
+			overridden = " (overridden)"
+		}
+		fmt.Fprintf(w, "%s\t# %s:%d [%s]%s\n", optionArg(t.Option, t.Value), t.File, t.Line, t.Section, overridden)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	var argv []string
+	for _, opt := range []struct {
+		name  string
+		value *string
+	}{
+		{"user", login.User},
+		{"password", login.Password},
+		{"host", login.Host},
+		{"socket", login.Socket},
+		{"port", login.Port},
+	} {
+		if opt.value != nil {
+			argv = append(argv, optionArg(opt.name, *opt.value))
+		}
+	}
+	fmt.Printf("\nmysql would have been started with the following arguments:\n%s\n", strings.Join(argv, " "))
+	return nil
+}
+
+// optionArg formats an option like mysql --print-defaults, with the
+// password hidden.
+func optionArg(name, value string) string {
+	if name == "password" {
+		value = "*****"
+	}
+	return "--" + name + "=" + value
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// renameFlag is a repeatable flag with values like "old=new".
+type renameFlag map[string]string
+
+func (r renameFlag) String() string {
+	var names []string
+	for from, to := range r {
+		names = append(names, from+"="+to)
+	}
+	return strings.Join(names, ",")
+}
+
+func (r renameFlag) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 || i == len(s)-1 {
+		return errors.New("expected <old>=<new>")
+	}
+	r[s[:i]] = s[i+1:]
+	return nil
+}
+
+// cmdExtract writes a subset of the sections to a new file with a fresh key.
+func cmdExtract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	output := flags.String("o", "", "output `file`")
+	force := flags.Bool("force", false, "overwrite the output file if it exists")
+	noPassword := flags.Bool("no-password", false, "drop passwords")
+	rename := make(renameFlag)
+	flags.Var(rename, "rename", "rename a section: `old=new` (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin extract [-file <mylogin.cnf>] -o <file> [-force] [-no-password] [-rename <old>=<new>] ... <pattern> ...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *output == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if !*force {
+		if _, err := os.Lstat(*output); err == nil {
+			return fmt.Errorf("%s: file exists", *output)
+		}
+	}
+
+	sections, err := readOpts.ReadSections(*filename)
+	if err != nil {
+		return err
+	}
+
+	extracted, err := sections.Extract(flags.Args(), &mylogin.ExtractOptions{
+		Rename:        rename,
+		DropPasswords: *noPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		return err
+	}
+	return mylogin.WriteFile(*output, mylogin.NewFile(key, nil, extracted))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/workbench"
+)
+
+// cmdImport imports connections from another tool as sections.
+func cmdImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	from := flags.String("from", "", "source `format`: workbench")
+	force := flags.Bool("force", false, "replace existing sections")
+	dryRun := flags.Bool("n", false, "dry run: report only, don't write the file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin import [-file <mylogin.cnf>] -from=workbench [-force] [-n] [<connections.xml>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *from != "workbench" || flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	source := workbench.DefaultFile()
+	if flags.NArg() == 1 {
+		source = flags.Arg(0)
+	}
+
+	conns, err := workbench.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	imported, issues := workbench.Import(conns)
+
+	var (
+		file     mylogin.File
+		sections mylogin.Sections
+	)
+	file, err = readOpts.ReadFile(*filename)
+	switch {
+	case err == nil:
+		if sections, err = mylogin.Parse(file.PlainText()); err != nil {
+			return err
+		}
+	case os.IsNotExist(err):
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			return err
+		}
+		file = mylogin.NewFile(key, nil, nil)
+	default:
+		return err
+	}
+
+	sections = sections.Normalize()
+	var count int
+	for _, s := range imported {
+		if !*force && sections.Login(s.Name) != nil {
+			issues = append(issues, mylogin.Issue{
+				Severity: mylogin.SeverityWarning,
+				Section:  s.Name,
+				Message:  "section exists: skipped (use -force to replace)",
+			})
+			continue
+		}
+		sections.Set(s)
+		count++
+		// Passwords are not shown
+		l := s.Login
+		l.Password = nil
+		fmt.Printf("[%s] %s\n", s.Name, l.DSN())
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+
+	if *dryRun || count == 0 {
+		return nil
+	}
+	return mylogin.WriteFile(*filename, mylogin.NewFile(file.Key(), file.ByteOrder(), sections))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdInspect dumps the binary structure of the file.
+func cmdInspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	asJSON := flags.Bool("json", false, "JSON output")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin inspect [-file <mylogin.cnf>] [-json]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	f, err := readOpts.Open(*filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	insp, err := mylogin.Inspect(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *filename, err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(insp)
+	} else {
+		err = printInspection(insp)
+	}
+	if err != nil {
+		return err
+	}
+	if !insp.IsClean() {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func printInspection(insp *mylogin.Inspection) error {
+	key, _ := insp.Key.MarshalText()
+	fmt.Printf("header:     % X\n", insp.Header[:])
+	fmt.Printf("key:        %X (%s)\n", insp.Key, key)
+	fmt.Printf("byte order: %s\n", insp.ByteOrder)
+	for _, a := range insp.Anomalies {
+		fmt.Printf("anomaly:    %s\n", a)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "#\toffset\tsize\tpadding\tlength\tkind\tname\tanomalies")
+	for i, c := range insp.Chunks {
+		padding := fmt.Sprint(c.Padding)
+		if !c.PaddingValid {
+			padding = "invalid"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			i, c.Offset, c.Size, padding, c.Length, c.Kind, c.Name, strings.Join(c.Anomalies, "; "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	if insp.End == "" {
+		fmt.Printf("end:        clean EOF at offset %d\n", insp.Size)
+	} else {
+		fmt.Printf("end:        %s\n", insp.End)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdLint reports problems in the file.
+func cmdLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	asJSON := flags.Bool("json", false, "JSON output")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin lint [-file <mylogin.cnf>] [-json]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	issues, err := lintFile(*filename)
+	if err != nil {
+		// Distinct from the exit codes of the issues
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(3)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+
+	// Exit code: 0 if clean, 1 for warnings, 2 for errors, 3 if unreadable
+	code := 0
+	for _, issue := range issues {
+		switch {
+		case issue.Severity >= mylogin.SeverityError:
+			code = 2
+		case issue.Severity == mylogin.SeverityWarning && code < 1:
+			code = 1
+		}
+	}
+	os.Exit(code)
+	return nil
+}
+
+// lintFile returns the issues of the file, including its permission
+// problems. An error is returned if the file can't be read.
+func lintFile(filename string) ([]mylogin.Issue, error) {
+	issues := []mylogin.Issue{}
+	for _, err := range mylogin.CheckPermissions(filename) {
+		if _, ok := err.(*mylogin.PermissionError); !ok {
+			return nil, err
+		}
+		issues = append(issues, mylogin.Issue{
+			Severity: mylogin.SeverityError,
+			Message:  err.Error(),
+		})
+	}
+
+	// Permissions are reported above
+	sections, err := (&mylogin.ReadOptions{Permissions: mylogin.PermissionIgnore}).ReadSections(filename)
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, sections.Validate()...), nil
+}
@@ -2,7 +2,117 @@
 //
 // # Usage
 //
-//	mylogin [-file ~/.mylogin.cnf] [-replay | -remove | -json | -template=<template> | -templateln=<template>] [<section> ...]
+//	mylogin [-file ~/.mylogin.cnf] [-format=<format>[:<argument>] | -replay | -replay-set | -remove | -json | -template=<template> | -templateln=<template> | -template-file=<file>] [<pattern> ...]
+//	mylogin exec [-file ~/.mylogin.cnf] [-keep-names] <section> ... -- <command> [<arg> ...]
+//	mylogin extract [-file ~/.mylogin.cnf] -o <file> [-force] [-no-password] [-rename <old>=<new>] ... <pattern> ...
+//	mylogin set [-file ~/.mylogin.cnf] [-G <section>] [-u <user>] [-p <password>] [-h <host>] [-P <port>] [-S <socket>]
+//	mylogin rekey [-file ~/.mylogin.cnf]
+//	mylogin encrypt [-key <key>] [-big-endian] [-o <file>] [<plaintext file>]
+//	mylogin inspect [-file ~/.mylogin.cnf] [-json]
+//	mylogin recover [-file ~/.mylogin.cnf] [-o <file> [-force]]
+//	mylogin lint [-file ~/.mylogin.cnf] [-json]
+//	mylogin explain [-file ~/.mylogin.cnf] [<login-path> ...]
+//	mylogin ping [-file ~/.mylogin.cnf] [-json] [-parallel 4] [-timeout 5s] [-protocol tcp|socket] [-defaults-file ~/.my.cnf] [<pattern> ...]
+//	mylogin rotate [-file ~/.mylogin.cnf] [-length 32] [-retain] [-timeout 10s] [-protocol tcp|socket] [-defaults-file ~/.my.cnf] <login-path>
+//	mylogin import [-file ~/.mylogin.cnf] -from=workbench [-force] [-n] [<connections.xml>]
+//	mylogin env [-file ~/.mylogin.cnf] [-shell bash|fish|powershell|dotenv] [-prefix MYSQL_] [-password] [<section> ...]
+//
+// Sections are selected by patterns: either a section name, a glob (see
+// [path.Match]) such as 'prod-*', or a regular expression enclosed in
+// slashes such as '/^prod-(ro|rw)$/'. A pattern that matches no section is
+// an error. Without output format, the file is dumped as is, and sections
+// given by name (not by a glob or regular expression) are dumped as they
+// are in the file.
+//
+// A section named like a command can be dumped with: mylogin -- <section>
+//
+// Output formats are defined in package [github.com/dolmen-go/mylogin/format].
+// Each format can be selected either with -format or with a flag of the
+// same name: "-format=json" is "-json", "-format=template:{{.user}}" is
+// "-template={{.user}}".
+//
+// # Commands
+//
+// exec runs a command (such as mysql or mysqldump) with an isolated
+// temporary login file that contains only the given sections, merged under
+// [client] (or kept under their own name with -keep-names). The temporary
+// file is exposed to the command with the MYSQL_TEST_LOGIN_FILE environment
+// variable and removed when the command exits.
+//
+// extract writes the sections matching the patterns to a new login file
+// encrypted with a fresh key, optionally renaming them and dropping
+// passwords. Example:
+//
+//	mylogin extract -o ci.cnf -rename prod-ro=client -no-password 'prod-ro' 'test-*'
+//
+// set creates or replaces a section, non-interactively (unlike
+// mysql_config_editor, the password is given on the command line). The file
+// is created with a fresh key if it doesn't exist. A full file can be
+// rebuilt on another machine from the output of "mylogin -replay-set".
+//
+// rekey re-encrypts the file with a fresh random key, keeping the content.
+//
+// encrypt encrypts a plaintext login file, with a fresh key or with the key
+// given in the compact form printed by mylogin-key (see
+// [mylogin.Key.UnmarshalText]), for example to build reproducible test
+// fixtures:
+//
+//	mylogin encrypt -key 08864298E84A96C6B9F08CA74 -o golden.cnf golden.txt
+//
+// inspect dumps the binary structure of the file, for debugging corruption:
+// header, key, byte order, and offset, size and padding of each chunk. Only
+// the names of sections and options are shown, not the values. Anything
+// that mysql_config_editor would not have written is reported as an
+// anomaly, and the exit code is then 1.
+//
+// recover decodes as much as possible of a corrupted file, skipping the
+// chunks that can't be decrypted (see [mylogin.Recover]). It reports the
+// recovered sections and what was lost, and can write a repaired file with
+// the same key. The exit code is 1 if anything was lost.
+//
+// lint reports problems in the file (see [mylogin.Sections.Validate]) and
+// unsafe file permissions (see [mylogin.CheckPermissions]). The exit code is
+// 2 if an error is found, 1 if a warning is found, and 3 if the file can't
+// be read, decrypted or parsed.
+//
+// Like the MySQL client, all the other commands refuse to read a file with
+// unsafe permissions. This can be changed with -permissions=warn (read the
+// file, report the problems on stderr) or -permissions=ignore.
+//
+// explain prints, in the style of mysql --print-defaults, the options
+// that the MySQL client would use with the given login paths, as [client]
+// merged with each login path. Each value is traced to its file, line and
+// section, and marked if overridden (see [mylogin.MergeTrace]). Passwords
+// are hidden.
+//
+// ping connects to the server with each selected section (default: all),
+// merged with [client], and runs "SELECT 1". It reports for each section:
+// ok, auth failure, unreachable, tls error or error, with the time taken.
+// TLS options can be read from the [client] group of an option file with
+// -defaults-file. The exit code is 1 if any check fails.
+//
+// rotate changes the password of a login path: it generates a random
+// password, connects with the current credentials (the login path merged
+// with [client]) to run ALTER USER CURRENT_USER() IDENTIFIED BY ...
+// (with RETAIN CURRENT PASSWORD if -retain), and checks that the new
+// password works. Only then the section is rewritten in the file. If the
+// check fails, the previous password is restored on the server.
+//
+// import adds the connections of MySQL Workbench (connections.xml, by
+// default from its standard location) as sections named after the
+// connections (see [github.com/dolmen-go/mylogin/workbench]). Existing
+// sections are kept unless -force is given. Connections through an SSH
+// tunnel are skipped, and options that can't be stored (such as the
+// default schema or TLS options) are reported. Passwords are usually
+// missing, as Workbench keeps them in the keychain: add them with
+// mysql_config_editor or "mylogin set". With -n the file is not written.
+//
+// env prints the merged login of the sections (default: client) as
+// assignments of the environment variables MYSQL_HOST, MYSQL_TCP_PORT,
+// MYSQL_UNIX_PORT, MYSQL_USER and, with -password, MYSQL_PWD. Values are
+// quoted for the selected shell. Example:
+//
+//	eval "$(mylogin env -prefix DB_ prod)"
 //
 // # Template output
 //
@@ -13,305 +123,278 @@
 // Examples:
 //
 //	mylogin '-templateln={{ json . }}'
+//
+// With -template-file, the template is read from a file and executed once
+// with the list of all sections (or the sections given as arguments), in
+// the order of the file. Each entry is a map with the same keys as with
+// -template: section, groupSuffix, user, password, host, port, socket.
+// Additional functions:
+//
+//   - json: JSON encoding
+//   - dsn: [github.com/go-sql-driver/mysql] connection string prefix of an entry
+//   - uri: mysql:// URI of an entry (the socket path is percent-encoded as host)
+//   - shquote: quote a string for POSIX shells
+//   - redact: replace a password (or the password of an entry) with "********"
+//   - merge: merge sections by name (see [mylogin.Sections.Merge]) into an
+//     entry; all the sections of the file are merged, even if not selected
+//
+// Example (an ssh_config-like file):
+//
+//	{{ range . }}Host {{ .section }}
+//	  HostName {{ .host }}
+//	  User {{ .user }}
+//	{{ end }}
 package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/format"
 )
 
-type outputFormat interface {
-	Help() (string, string)
-	flag.Getter
-	Print(w io.Writer, section *mylogin.Section) error
-}
-
-// outputFormatBool is an abstract base format for formats defined as a bool CLI flag.
-type outputFormatBool struct {
-	bool
+// formatFlag is the flag.Value of the -format flag and of the legacy flags
+// named like each format (-json, -template=<template>...).
+type formatFlag struct {
+	name     string          // "format" or the name of a legacy flag
+	selected *[]formatChoice // Shared by all flags
 }
 
-func (outputFormatBool) IsBoolFlag() bool {
-	return true
+// formatChoice is a format selected on the command line.
+type formatChoice struct {
+	flag   string
+	format format.Formatter
 }
 
-func (f *outputFormatBool) String() string {
-	return strconv.FormatBool(f.bool)
-}
-
-func (f *outputFormatBool) Set(s string) error {
-	ok, err := strconv.ParseBool(s)
-	if err != nil {
-		return err
+func (f formatFlag) String() string {
+	if f.selected == nil {
+		return ""
 	}
-	f.bool = ok
-	return nil
-}
-
-func (f *outputFormatBool) Get() interface{} {
-	if !f.bool {
-		return nil
+	for _, c := range *f.selected {
+		if c.flag == f.name {
+			name, _ := c.format.Help()
+			return name
+		}
 	}
-	return true
+	return ""
 }
 
-type formatReplay struct {
-	outputFormatBool
-}
-
-func (formatReplay) Help() (string, string) {
-	return "replay", "mysql_config_editor 'set' command format (note: password is not exported)"
-}
-
-func (formatReplay) Print(w io.Writer, section *mylogin.Section) error {
-	args := make([]string, 5, 5+5*2)
-	args[0] = `mysql_config_editor`
-	args[1] = `set`
-	args[2] = `--skip-warn`
-	args[3] = `-G`
-	args[4] = section.Name
-	if section.Login.User != nil {
-		args = append(args, `-u`, *section.Login.User)
-	}
-	if section.Login.Password != nil {
-		args = append(args, `-p`)
+// IsBoolFlag makes legacy flags of formats without argument bool flags.
+func (f formatFlag) IsBoolFlag() bool {
+	if f.name == "format" {
+		return false
 	}
-	if section.Login.Host != nil {
-		args = append(args, `-h`, *section.Login.Host)
-	}
-	if section.Login.Port != nil {
-		args = append(args, `-P`, *section.Login.Port)
-	}
-	if section.Login.Socket != nil {
-		args = append(args, `-S`, *section.Login.Socket)
-	}
-	_, err := fmt.Fprintln(w, strings.Join(args, " "))
-	return err
+	_, configurable := format.Lookup(f.name).(format.Configurable)
+	return !configurable
 }
 
-type formatRemove struct {
-	outputFormatBool
-}
-
-func (formatRemove) Help() (string, string) {
-	return "remove", "mysql_config_editor 'remove' command format"
-}
-
-func (formatRemove) Print(w io.Writer, section *mylogin.Section) error {
-	_, err := fmt.Fprintln(w, "mysql_config_editor remove -G", section.Name)
-	return err
-}
-
-func loginAsMap(login *mylogin.Login) map[string]interface{} {
-	// The login struct contains *string
-	// This is not convenient to use in templates
-	// So we remap it to a map, skipping nil values
-	m := make(map[string]interface{})
-	for _, x := range []struct {
-		key   string
-		value *string
-	}{
-		{"user", login.User},
-		{"password", login.Password},
-		{"host", login.Host},
-		{"socket", login.Socket},
-		{"port", login.Port},
-	} {
-		if x.value != nil {
-			m[x.key] = *x.value
+func (f formatFlag) Set(s string) error {
+	name, arg := f.name, s
+	if name == "format" {
+		// -format=<name>[:<argument>]
+		name = s
+		arg = ""
+		if i := strings.IndexByte(s, ':'); i >= 0 {
+			name, arg = s[:i], s[i+1:]
+		}
+	} else if f.IsBoolFlag() {
+		ok, err := strconv.ParseBool(s)
+		if err != nil || !ok {
+			return err
 		}
+		arg = ""
 	}
 
-	return m
-}
-
-type formatJSON struct {
-	outputFormatBool
-}
-
-func (formatJSON) Help() (string, string) {
-	return "json", "JSON format (note: section name is not exported)"
-}
-
-func (formatJSON) Print(w io.Writer, section *mylogin.Section) error {
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetEscapeHTML(false)
-	enc.SetIndent("", "  ")
-	return enc.Encode(loginAsMap(&section.Login))
-}
-
-type formatTemplate struct {
-	tmpl *template.Template
-}
-
-func (formatTemplate) Help() (string, string) {
-	return "template", "text/template format (additional function: 'json')"
-}
-
-func (f *formatTemplate) String() string {
-	if f.tmpl == nil {
-		return ""
+	ft := format.Lookup(name)
+	if ft == nil {
+		return fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(format.Names(), ", "))
 	}
-	return "<template>"
-}
-
-func (f *formatTemplate) Set(s string) error {
-	tmpl, err := template.New("user-template").Funcs(
-		template.FuncMap{
-			"json": func(v interface{}) (string, error) {
-				b, err := json.Marshal(v)
-				if err != nil {
-					return "", err
-				}
-				return string(b), nil
-			},
-		},
-	).Parse(s)
-	if err != nil {
-		return err
+	if c, ok := ft.(format.Configurable); ok {
+		if err := c.Set(arg); err != nil {
+			return err
+		}
+	} else if arg != "" {
+		return fmt.Errorf("format %s doesn't take an argument", name)
 	}
-	(*f).tmpl = tmpl
+	*f.selected = append(*f.selected, formatChoice{flag: f.name, format: ft})
 	return nil
 }
 
-func (f *formatTemplate) Get() interface{} {
-	if f.tmpl == nil {
-		return nil
-	}
-	return f
+// commands are the subcommands, selected by the first command-line argument.
+var commands = map[string]func(args []string) error{
+	"encrypt": cmdEncrypt,
+	"env":     cmdEnv,
+	"exec":    cmdExec,
+	"explain": cmdExplain,
+	"extract": cmdExtract,
+	"import":  cmdImport,
+	"inspect": cmdInspect,
+	"lint":    cmdLint,
+	"ping":    cmdPing,
+	"recover": cmdRecover,
+	"rekey":   cmdRekey,
+	"rotate":  cmdRotate,
+	"set":     cmdSet,
 }
 
-func (f *formatTemplate) Print(w io.Writer, section *mylogin.Section) error {
-	m := loginAsMap(&section.Login)
-
-	m["section"] = section.Name
-	// Export trucated section name to use with --defaults-group-suffix option of the MySQL CLI
-	const sectionSuffix = "groupSuffix"
-	switch {
-	case strings.HasPrefix(section.Name, "mysql"):
-		m[sectionSuffix] = section.Name[5:]
-	case strings.HasPrefix(section.Name, "client"):
-		m[sectionSuffix] = section.Name[6:]
-	}
-
-	err := f.tmpl.Execute(os.Stdout, m)
-	if err != nil {
-		return err
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
 	}
-	_, err = fmt.Fprint(w)
-	return err
-}
 
-type formatTemplateLn struct {
-	formatTemplate
-}
-
-func (formatTemplateLn) Help() (string, string) {
-	return "templateln", "text/template format (additional function: 'json') with trailing line break"
-}
-
-func (f *formatTemplateLn) Set(s string) error {
-	return f.formatTemplate.Set(s + "\n")
-}
-
-func main() {
 	var filename string
 	flag.StringVar(&filename, "file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flag.CommandLine)
 
-	formats := []outputFormat{
-		&formatReplay{},
-		&formatRemove{},
-		&formatJSON{},
-		&formatTemplate{},
-		&formatTemplateLn{},
-	}
-
-	for _, fmt := range formats {
-		name, usage := fmt.Help()
-		flag.Var(fmt, name, usage)
+	var choices []formatChoice
+	for _, ft := range format.Formatters() {
+		name, usage := ft.Help()
+		flag.Var(formatFlag{name: name, selected: &choices}, name, usage)
 	}
+	flag.Var(formatFlag{name: "format", selected: &choices}, "format", "output `format`: <name>[:<argument>] (available: "+strings.Join(format.Names(), ", ")+")")
 
 	flag.Parse()
 
-	var selectedFormat outputFormat
-	for _, ft := range formats {
-		f := ft.Get()
-		if f == nil {
-			continue
-		}
-		if selectedFormat != nil {
-			h1, _ := ft.Help()
-			h2, _ := selectedFormat.Help()
-			fmt.Fprintf(os.Stderr, "options -%s and -%s are exclusive.\n", h1, h2)
-			flag.Usage()
-			os.Exit(1)
-		}
-		selectedFormat = ft
+	var selectedFormat format.Formatter
+	if len(choices) > 1 {
+		fmt.Fprintf(os.Stderr, "options -%s and -%s are exclusive.\n", choices[1].flag, choices[0].flag)
+		flag.Usage()
+		os.Exit(1)
+	} else if len(choices) == 1 {
+		selectedFormat = choices[0].format
 	}
 
-	if selectedFormat != nil {
-
-		if flag.NArg() != 0 {
+	// Without format, the plaintext is dumped as is, unless patterns have to
+	// be matched
+	if selectedFormat == nil && !hasPattern(flag.Args()) {
+		file, err := readOpts.Open(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
 
-			for _, name := range flag.Args() {
-				login, err := mylogin.ReadLogin(filename, []string{name})
-				if err != nil {
-					log.Fatal(err)
-				}
-				if login == nil {
-					log.Fatal("section doesn't exists")
-				}
+		f, err := mylogin.Decode(bufio.NewReader(file))
+		if err != nil {
+			log.Fatal(err)
+		}
 
-				err = selectedFormat.Print(os.Stdout, &mylogin.Section{Name: name, Login: *login})
-				if err != nil {
-					log.Fatal(err)
-				}
-			}
-		} else {
-			sections, err := mylogin.ReadSections(filename)
+		if flag.NArg() == 0 {
+			_, err = io.Copy(os.Stdout, f.PlainText())
 			if err != nil {
 				log.Fatal(err)
 			}
-
-			for i := range sections {
-				err = selectedFormat.Print(os.Stdout, &sections[i])
-				if err != nil {
-					log.Fatal(err)
-				}
-			}
-
+			return
 		}
 
-	} else {
-		file, err := os.Open(filename)
+		plainText, err := ioutil.ReadAll(f.PlainText())
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer file.Close()
+		for _, name := range flag.Args() {
+			n, err := io.Copy(os.Stdout, mylogin.FilterSection(bytes.NewReader(plainText), name))
+			if err != nil {
+				log.Fatal(err)
+			}
+			if n == 0 {
+				log.Fatalf("no section matches %q", name)
+			}
+		}
+		return
+	}
 
-		f, err := mylogin.Decode(bufio.NewReader(file))
+	all, err := readOpts.ReadSections(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sections := all
+	if flag.NArg() != 0 {
+		sections, err = selectSections(all, flag.Args())
 		if err != nil {
 			log.Fatal(err)
 		}
-		rd := f.PlainText()
+	}
+
+	if selectedFormat == nil {
+		_, err = sections.WriteTo(os.Stdout)
+	} else {
+		if f, ok := selectedFormat.(format.FileSetter); ok {
+			f.SetFile(all)
+		}
+		err = format.Format(os.Stdout, selectedFormat, sections)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// permissionsFlag is the -permissions flag, that sets the policy of
+// ReadOptions for a file with unsafe permissions.
+type permissionsFlag struct {
+	opts *mylogin.ReadOptions
+}
+
+func (f permissionsFlag) String() string {
+	if f.opts == nil {
+		return ""
+	}
+	return f.opts.Permissions.String()
+}
+
+func (f permissionsFlag) Set(s string) error {
+	return f.opts.Permissions.UnmarshalText([]byte(s))
+}
 
-		if flag.NArg() > 0 {
-			rd = mylogin.FilterSection(rd, flag.Arg(0))
+// readOptions registers the -permissions flag and returns the options to
+// read the login file with. With -permissions=warn the problems are
+// reported on stderr.
+func readOptions(flags *flag.FlagSet) *mylogin.ReadOptions {
+	opts := &mylogin.ReadOptions{
+		Warn: func(err error) {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		},
+	}
+	flags.Var(permissionsFlag{opts}, "permissions", "`policy` for a file with unsafe permissions: refuse, warn or ignore")
+	return opts
+}
+
+// hasPattern reports if any of the arguments is a glob or a regular
+// expression, not just a section name (see mylogin.Sections.Select).
+func hasPattern(args []string) bool {
+	for _, arg := range args {
+		if strings.ContainsAny(arg, `*?[\`) || (len(arg) >= 2 && arg[0] == '/' && arg[len(arg)-1] == '/') {
+			return true
 		}
+	}
+	return false
+}
 
-		_, err = io.Copy(os.Stdout, rd)
+// selectSections returns the sections matching the patterns, in the order
+// of the patterns (see mylogin.Sections.Select).
+func selectSections(sections mylogin.Sections, patterns []string) (mylogin.Sections, error) {
+	var selected mylogin.Sections
+	for _, pattern := range patterns {
+		s, err := sections.Select(pattern)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
+		}
+		if len(s) == 0 {
+			return nil, fmt.Errorf("no section matches %q", pattern)
 		}
+		selected = append(selected, s...)
 	}
+	return selected, nil
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/go-sql-driver/mysql"
+)
+
+// Status of a ping
+const (
+	pingOK          = "ok"
+	pingAuthFailure = "auth failure"
+	pingUnreachable = "unreachable"
+	pingTLSError    = "tls error"
+	pingError       = "error"
+)
+
+// pingResult is the result of the check of a section.
+type pingResult struct {
+	Section string  `json:"section"`
+	Status  string  `json:"status"`
+	Error   string  `json:"error,omitempty"`
+	Millis  float64 `json:"ms"`
+}
+
+// pingOptions are the options of pingAll.
+type pingOptions struct {
+	Parallel int
+	connOptions
+}
+
+// cmdPing connects with each selected section and runs a trivial query.
+func cmdPing(args []string) error {
+	flags := flag.NewFlagSet("ping", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	asJSON := flags.Bool("json", false, "JSON output")
+	var opts pingOptions
+	flags.IntVar(&opts.Parallel, "parallel", 4, "maximum number of parallel checks")
+	opts.registerFlags(flags, 5*time.Second)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin ping [-file <mylogin.cnf>] [-json] [-parallel <n>] [-timeout <duration>] [-protocol tcp|socket] [-defaults-file <my.cnf>] [<pattern> ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if opts.Parallel < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := opts.registerTLS("mylogin-ping"); err != nil {
+		return err
+	}
+
+	sections, err := readOpts.ReadSections(*filename)
+	if err != nil {
+		return err
+	}
+	sections = sections.Normalize()
+	selected := sections
+	if flags.NArg() != 0 {
+		if selected, err = selectSections(sections, flags.Args()); err != nil {
+			return err
+		}
+	}
+	names := make([]string, len(selected))
+	for i := range selected {
+		names[i] = selected[i].Name
+	}
+
+	results := pingAll(sections, names, &opts)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%.0fms\t%s\n", r.Section, r.Status, r.Millis, r.Error)
+		}
+		err = w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if r.Status != pingOK {
+			os.Exit(1)
+		}
+	}
+	return nil
+}
+
+// pingAll checks the sections with the given names, merged with [client]
+// of sections like the MySQL client does, with at most opts.Parallel checks
+// at the same time. The results are in the order of names.
+func pingAll(sections mylogin.Sections, names []string, opts *pingOptions) []pingResult {
+	results := make([]pingResult, len(names))
+	sem := make(chan struct{}, opts.Parallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		merged := []string{mylogin.DefaultSection}
+		if name != mylogin.DefaultSection {
+			merged = append(merged, name)
+		}
+		login := sections.Merge(merged)
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = ping(name, login, opts)
+			<-sem
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// ping connects with login and runs "SELECT 1".
+func ping(section string, login *mylogin.Login, opts *pingOptions) pingResult {
+	start := time.Now()
+	err := pingLogin(login, opts)
+	r := pingResult{
+		Section: section,
+		Status:  pingStatus(err),
+		Millis:  float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func pingLogin(login *mylogin.Login, opts *pingOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	return checkLogin(ctx, login, &opts.connOptions)
+}
+
+// checkLogin connects with login and runs "SELECT 1".
+func checkLogin(ctx context.Context, login *mylogin.Login, opts *connOptions) error {
+	db, err := opts.open(login)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var one int
+	return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// pingStatus classifies the error of a ping.
+func pingStatus(err error) string {
+	switch err := err.(type) {
+	case nil:
+		return pingOK
+	case *mysql.MySQLError:
+		switch err.Number {
+		case 1044, 1045, 1698, 1251, 1862: // ER_DBACCESS_DENIED_ERROR, ER_ACCESS_DENIED_ERROR...
+			return pingAuthFailure
+		}
+		return pingError
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+		return pingTLSError
+	}
+	if err == mysql.ErrNoTLS || strings.Contains(err.Error(), "tls:") || strings.Contains(err.Error(), "x509:") {
+		return pingTLSError
+	}
+	if _, ok := err.(net.Error); ok {
+		return pingUnreachable
+	}
+	if err == context.DeadlineExceeded {
+		return pingUnreachable
+	}
+	return pingError
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/internal/fakemysql"
+	"github.com/dolmen-go/mylogin/mysqltls"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestPing(t *testing.T) {
+	srv := fakemysql.New(map[string]string{"alice": "secret", "bob": ""})
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Addr())
+
+	tlsCfg, err := fakemysql.SelfSignedTLS("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvTLS := fakemysql.New(map[string]string{"alice": "secret"})
+	srvTLS.TLS = tlsCfg
+	if err = srvTLS.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srvTLS.Close()
+	_, portTLS, _ := net.SplitHostPort(srvTLS.Addr())
+
+	// A port where nothing listens
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{Host: &host, Port: &port}},
+		{Name: "alice", Login: mylogin.Login{User: stringPtr("alice"), Password: stringPtr("secret")}},
+		{Name: "bob", Login: mylogin.Login{User: stringPtr("bob")}},
+		{Name: "wrong", Login: mylogin.Login{User: stringPtr("alice"), Password: stringPtr("wrong")}},
+		{Name: "down", Login: mylogin.Login{User: stringPtr("alice"), Port: &closedPort}},
+	}
+	opts := pingOptions{Parallel: 2, connOptions: connOptions{Timeout: 5 * time.Second}}
+	for _, test := range []struct {
+		names    []string
+		expected []string
+	}{
+		{
+			[]string{"client", "alice", "bob", "wrong", "down"},
+			[]string{pingAuthFailure, pingOK, pingOK, pingAuthFailure, pingUnreachable},
+		},
+		// A subset is still merged with [client] (host and port)
+		{[]string{"bob", "alice"}, []string{pingOK, pingOK}},
+	} {
+		results := pingAll(sections, test.names, &opts)
+		if len(results) != len(test.expected) {
+			t.Fatalf("%q: got %d results", test.names, len(results))
+		}
+		for i, r := range results {
+			if r.Section != test.names[i] || r.Status != test.expected[i] {
+				t.Errorf("%s: got %+v, expected %s", test.names[i], r, test.expected[i])
+			}
+			if r.Millis <= 0 {
+				t.Errorf("%s: no timing", r.Section)
+			}
+		}
+	}
+
+	// TLS
+	for _, test := range []struct {
+		port     string
+		tls      mylogin.TLSOptions
+		expected string
+	}{
+		{portTLS, mylogin.TLSOptions{Mode: mylogin.SSLModeRequired}, pingOK},
+		{portTLS, mylogin.TLSOptions{Mode: mylogin.SSLModeVerifyIdentity}, pingTLSError},
+		{port, mylogin.TLSOptions{Mode: mylogin.SSLModeRequired}, pingTLSError},
+		// Like the mysql client, PREFERRED (the default) connects to a server
+		// without TLS
+		{port, mylogin.TLSOptions{}, pingOK},
+		{port, mylogin.TLSOptions{Mode: mylogin.SSLModePreferred}, pingOK},
+	} {
+		name, err := mysqltls.Register("mylogin-ping-test", &test.tls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.TLS = name
+		login := &mylogin.Login{User: stringPtr("alice"), Password: stringPtr("secret"), Host: &host, Port: &test.port}
+		if r := ping("tls", login, &opts); r.Status != test.expected {
+			t.Errorf("%s %s: got %+v, expected %s", test.port, test.tls.Mode, r, test.expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdRecover recovers the sections of a corrupted file.
+func cmdRecover(args []string) error {
+	flags := flag.NewFlagSet("recover", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	output := flags.String("o", "", "write the repaired file to `file`")
+	force := flags.Bool("force", false, "overwrite the output file if it exists")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin recover [-file <mylogin.cnf>] [-o <file> [-force]]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *output != "" && !*force {
+		if _, err := os.Lstat(*output); err == nil {
+			return fmt.Errorf("%s: file exists", *output)
+		}
+	}
+
+	f, err := readOpts.Open(*filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rec, err := mylogin.Recover(bufio.NewReader(f))
+	if err != nil {
+		return fmt.Errorf("%s: %v", *filename, err)
+	}
+
+	for _, s := range rec.Sections {
+		fmt.Printf("recovered: [%s]", s.Name)
+		for _, opt := range []struct {
+			name  string
+			value *string
+		}{
+			{"user", s.Login.User},
+			{"password", s.Login.Password},
+			{"host", s.Login.Host},
+			{"socket", s.Login.Socket},
+			{"port", s.Login.Port},
+		} {
+			if opt.value != nil {
+				fmt.Print(" ", opt.name)
+			}
+		}
+		fmt.Println()
+	}
+	for _, lost := range rec.Lost {
+		fmt.Println("lost:", lost)
+	}
+	for _, o := range rec.Orphans {
+		fmt.Printf("dropped: option %q at offset %d (its section is unknown)\n", o.Option, o.Offset)
+	}
+
+	if *output != "" {
+		if err = mylogin.WriteFile(*output, rec.File); err != nil {
+			return err
+		}
+	}
+
+	if len(rec.Lost) > 0 || len(rec.Orphans) > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// cmdRekey re-encrypts the file with a new key.
+func cmdRekey(args []string) error {
+	flags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin rekey [-file <mylogin.cnf>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	return readOpts.Rekey(*filename, rand.Reader)
+}
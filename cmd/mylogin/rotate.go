@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// passwordChars are the characters of generated passwords. Quotes,
+// backslash and the separators of DSNs are excluded.
+const passwordChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_.!*%+=~^"
+
+// rotateOptions are the options of rotate.
+type rotateOptions struct {
+	Length int
+	Retain bool
+	// Read are the options to read the file
+	Read mylogin.ReadOptions
+	connOptions
+}
+
+// cmdRotate changes the password of a login path on the server and in the
+// file.
+func cmdRotate(args []string) error {
+	flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	var opts rotateOptions
+	flags.IntVar(&opts.Length, "length", 32, "length of the new password")
+	flags.BoolVar(&opts.Retain, "retain", false, "keep the current password valid on the server (RETAIN CURRENT PASSWORD, MySQL 8.0.14+)")
+	opts.registerFlags(flags, 10*time.Second)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin rotate [-file <mylogin.cnf>] [-length <n>] [-retain] [-timeout <duration>] [-protocol tcp|socket] [-defaults-file <my.cnf>] <login-path>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || opts.Length < 8 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	opts.Read = *readOpts
+
+	if err := opts.registerTLS("mylogin-rotate"); err != nil {
+		return err
+	}
+
+	return rotate(*filename, flags.Arg(0), &opts)
+}
+
+// rotate generates a new password, changes it on the server with the
+// current credentials of the section (merged with [client]), checks that it
+// works, and only then replaces the password of the section in the file.
+func rotate(filename, section string, opts *rotateOptions) error {
+	file, err := opts.Read.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	sections, err := mylogin.Parse(file.PlainText())
+	if err != nil {
+		return err
+	}
+	sections = sections.Normalize()
+	if sections.Login(section) == nil {
+		return fmt.Errorf("%s: section doesn't exist", section)
+	}
+	names := []string{mylogin.DefaultSection}
+	if section != mylogin.DefaultSection {
+		names = append(names, section)
+	}
+	login := sections.Merge(names)
+
+	password, err := newPassword(opts.Length)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	db, err := opts.open(login)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connect with the current password: %v", err)
+	}
+	defer conn.Close()
+
+	query := "ALTER USER CURRENT_USER() IDENTIFIED BY " + quoteSQL(password)
+	if opts.Retain {
+		query += " RETAIN CURRENT PASSWORD"
+	}
+	if _, err = conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("change password: %v", err)
+	}
+
+	// restore restores the previous password, so the file stays valid. It
+	// has its own timeout, as the time of ctx may have been used.
+	restore := func() error {
+		var oldPassword string
+		if login.Password != nil {
+			oldPassword = *login.Password
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+		_, err := conn.ExecContext(ctx, "ALTER USER CURRENT_USER() IDENTIFIED BY "+quoteSQL(oldPassword))
+		return err
+	}
+
+	newLogin := *login
+	newLogin.Password = &password
+	if err = checkLogin(ctx, &newLogin, &opts.connOptions); err != nil {
+		if errRestore := restore(); errRestore != nil {
+			return fmt.Errorf("the new password doesn't work (%v) and the previous password could not be restored (%v)", err, errRestore)
+		}
+		return fmt.Errorf("the new password doesn't work, the previous password has been restored: %v", err)
+	}
+
+	for i := range sections {
+		if sections[i].Name == section {
+			sections[i].Login.Password = &password
+		}
+	}
+	if err = mylogin.WriteFile(filename, mylogin.NewFile(file.Key(), file.ByteOrder(), sections)); err != nil {
+		if errRestore := restore(); errRestore != nil {
+			// Last chance to not lose the access to the account
+			fmt.Fprintf(os.Stderr, "new password of [%s]: %s\n", section, password)
+			return fmt.Errorf("the file could not be written (%v) and the previous password could not be restored (%v): the new password has been printed", err, errRestore)
+		}
+		return fmt.Errorf("the file could not be written, the previous password has been restored: %v", err)
+	}
+	return nil
+}
+
+// newPassword generates a random password of n characters from
+// passwordChars.
+func newPassword(n int) (string, error) {
+	max := big.NewInt(int64(len(passwordChars)))
+	b := make([]byte, n)
+	for i := range b {
+		j, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordChars[j.Int64()]
+	}
+	return string(b), nil
+}
+
+// quoteSQL quotes s as an SQL string literal.
+func quoteSQL(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/internal/fakemysql"
+)
+
+func TestRotate(t *testing.T) {
+	alterUser := regexp.MustCompile(`^ALTER USER CURRENT_USER\(\) IDENTIFIED BY '((?:[^'\\]|\\.)*)'( RETAIN CURRENT PASSWORD)?$`)
+	var (
+		queries     []string
+		broken      bool   // ALTER USER doesn't change the password
+		onAlter     func() // called before the password is changed
+		failRestore bool   // the second ALTER USER fails
+	)
+	srv := fakemysql.New(map[string]string{"alice": "old"})
+	srv.Query = func(user, query string) ([]string, error) {
+		queries = append(queries, query)
+		m := alterUser.FindStringSubmatch(query)
+		if m == nil {
+			return nil, errors.New("unexpected query")
+		}
+		if onAlter != nil {
+			onAlter()
+		}
+		if failRestore && len(queries) == 2 {
+			return nil, errors.New("restore failure")
+		}
+		if !broken {
+			srv.SetPassword(user, strings.NewReplacer(`\\`, `\`, `\'`, `'`).Replace(m[1]))
+		}
+		return nil, nil
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Addr())
+
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	home := filepath.Join(dir, "home")
+	filename := filepath.Join(home, ".mylogin.cnf")
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeSections := func(password string) {
+		t.Helper()
+		if err := os.MkdirAll(home, 0700); err != nil {
+			t.Fatal(err)
+		}
+		sections := mylogin.Sections{
+			{Name: "client", Login: mylogin.Login{Host: &host, Port: &port}},
+			{Name: "app", Login: mylogin.Login{User: stringPtr("alice"), Password: &password}},
+		}
+		if err := mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePassword := func() string {
+		t.Helper()
+		sections, err := mylogin.ReadSections(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *sections.Login("app").Password
+	}
+	opts := rotateOptions{Length: 32, connOptions: connOptions{Timeout: 5 * time.Second}}
+
+	writeSections("old")
+	if err = rotate(filename, "app", &opts); err != nil {
+		t.Fatal(err)
+	}
+	password := filePassword()
+	if serverPassword, _ := srv.Password("alice"); password != serverPassword || len(password) != 32 || password == "old" {
+		t.Errorf("file: %q, server: %q", password, serverPassword)
+	}
+
+	// RETAIN CURRENT PASSWORD
+	opts.Retain = true
+	queries = nil
+	if err = rotate(filename, "app", &opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 || !strings.HasSuffix(queries[0], " RETAIN CURRENT PASSWORD") {
+		t.Errorf("queries: %q", queries)
+	}
+	opts.Retain = false
+
+	// Wrong current password: nothing changes
+	writeSections("wrong")
+	if err = rotate(filename, "app", &opts); err == nil {
+		t.Error("error expected")
+	}
+	if filePassword() != "wrong" {
+		t.Error("file changed")
+	}
+
+	// The new password doesn't work: the previous one is restored
+	srv.SetPassword("alice", "old")
+	writeSections("old")
+	broken = true
+	queries = nil
+	if err = rotate(filename, "app", &opts); err == nil {
+		t.Error("error expected")
+	}
+	if filePassword() != "old" {
+		t.Error("file changed")
+	}
+	if len(queries) != 2 || queries[1] != "ALTER USER CURRENT_USER() IDENTIFIED BY 'old'" {
+		t.Errorf("queries: %q", queries)
+	}
+
+	if err = rotate(filename, "missing", &opts); err == nil {
+		t.Error("error expected")
+	}
+
+	// The file can't be written: the previous password is restored
+	broken = false
+	writeSections("old")
+	queries = nil
+	onAlter = func() { os.RemoveAll(home) }
+	if err = rotate(filename, "app", &opts); err == nil {
+		t.Error("error expected")
+	}
+	if serverPassword, _ := srv.Password("alice"); serverPassword != "old" {
+		t.Errorf("server: %q", serverPassword)
+	}
+	if len(queries) != 2 || queries[1] != "ALTER USER CURRENT_USER() IDENTIFIED BY 'old'" {
+		t.Errorf("queries: %q", queries)
+	}
+
+	// ... and if the restore fails, the new password is printed
+	writeSections("old")
+	queries = nil
+	failRestore = true
+	stderr := os.Stderr
+	os.Stderr, err = ioutil.TempFile(dir, "stderr-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rotate(filename, "app", &opts)
+	os.Stderr.Close()
+	output, _ := ioutil.ReadFile(os.Stderr.Name())
+	os.Stderr = stderr
+	if err == nil {
+		t.Error("error expected")
+	}
+	serverPassword, _ := srv.Password("alice")
+	if serverPassword == "old" || string(output) != "new password of [app]: "+serverPassword+"\n" {
+		t.Errorf("server: %q, stderr: %q", serverPassword, output)
+	}
+}
+
+func TestNewPassword(t *testing.T) {
+	p1, err := newPassword(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _ := newPassword(20)
+	if len(p1) != 20 || p1 == p2 || strings.Trim(p1, passwordChars) != "" {
+		t.Errorf("got %q, %q", p1, p2)
+	}
+}
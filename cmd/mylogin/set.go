@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// optionalString is a flag.Value that sets a *string only if the flag is
+// given, so an empty value can be distinguished from a missing one.
+type optionalString struct {
+	p **string
+}
+
+func (o optionalString) String() string {
+	if o.p == nil || *o.p == nil {
+		return ""
+	}
+	return **o.p
+}
+
+func (o optionalString) Set(s string) error {
+	*o.p = &s
+	return nil
+}
+
+// cmdSet creates or replaces a section, non-interactively.
+func cmdSet(args []string) error {
+	flags := flag.NewFlagSet("set", flag.ExitOnError)
+	filename := flags.String("file", mylogin.DefaultFile(), "mylogin.cnf path")
+	readOpts := readOptions(flags)
+	section := mylogin.Section{Name: mylogin.DefaultSection}
+	login := &section.Login
+	for _, opt := range []struct {
+		short, long string
+		value       flag.Value
+		usage       string
+	}{
+		{"G", "login-path", (*stringValue)(&section.Name), "section name"},
+		{"u", "user", optionalString{&login.User}, "user name"},
+		{"p", "password", optionalString{&login.Password}, "password (visible in the process list)"},
+		{"h", "host", optionalString{&login.Host}, "host name"},
+		{"P", "port", optionalString{&login.Port}, "TCP port"},
+		{"S", "socket", optionalString{&login.Socket}, "Unix socket path"},
+	} {
+		flags.Var(opt.value, opt.short, opt.usage)
+		flags.Var(opt.value, opt.long, opt.usage)
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mylogin set [-file <mylogin.cnf>] [-G <section>] [-u <user>] [-p <password>] [-h <host>] [-P <port>] [-S <socket>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 || section.Name == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		file     mylogin.File
+		sections mylogin.Sections
+		err      error
+	)
+	file, err = readOpts.ReadFile(*filename)
+	switch {
+	case err == nil:
+		if sections, err = mylogin.Parse(file.PlainText()); err != nil {
+			return err
+		}
+	case os.IsNotExist(err):
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			return err
+		}
+		file = mylogin.NewFile(key, nil, nil)
+	default:
+		return err
+	}
+
+	// Fold duplicates so that the new section is the only one with that name
+	sections = sections.Normalize()
+	sections.Set(section)
+	return mylogin.WriteFile(*filename, mylogin.NewFile(file.Key(), file.ByteOrder(), sections))
+}
+
+// stringValue is a flag.Value for a string.
+type stringValue string
+
+func (s *stringValue) String() string {
+	return string(*s)
+}
+
+func (s *stringValue) Set(v string) error {
+	*s = stringValue(v)
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/dolmen-go/mylogin/format"
+)
+
+// fishQuote quotes s for the fish shell.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// powershellQuote quotes s as a PowerShell verbatim string.
+func powershellQuote(s string) string {
+	// PowerShell also handles typographic single quotes as quotes
+	return "'" + strings.NewReplacer(
+		"'", "''",
+		"‘", "‘‘",
+		"’", "’’",
+		"‚", "‚‚",
+		"‛", "‛‛",
+	).Replace(s) + "'"
+}
+
+// dotenvQuote quotes s for .env files.
+//
+// Single quotes are used if possible as their content is not interpolated.
+// Else double quotes are used, with escaping of '\', '"', '$' and line breaks.
+func dotenvQuote(s string) string {
+	if !strings.ContainsAny(s, "'\n\r") {
+		return "'" + s + "'"
+	}
+	return `"` + strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`$`, `\$`,
+		"\n", `\n`,
+		"\r", `\r`,
+	).Replace(s) + `"`
+}
+
+// shells maps shell names to a function that formats a variable assignment.
+var shells = map[string]func(name, value string) string{
+	"bash": func(name, value string) string {
+		return "export " + name + "=" + format.ShQuote(value)
+	},
+	"fish": func(name, value string) string {
+		return "set -gx " + name + " " + fishQuote(value)
+	},
+	"powershell": func(name, value string) string {
+		return "$Env:" + name + " = " + powershellQuote(value)
+	},
+	"dotenv": func(name, value string) string {
+		return name + "=" + dotenvQuote(value)
+	},
+}
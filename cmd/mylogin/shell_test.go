@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// tricky are values with the characters that need escaping in at least one
+// of the shells.
+var tricky = []string{
+	``,
+	`simple`,
+	`it's`,
+	`say "hi"`,
+	`$HOME`,
+	"`id`",
+	`back\slash`,
+	`\'`,
+	"line1\nline2",
+	"cr\r\n",
+	`'"$` + "`\\\n",
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		quote    func(string) string
+		expected []string
+	}{
+		{"fish", fishQuote, []string{
+			`''`,
+			`'simple'`,
+			`'it\'s'`,
+			`'say "hi"'`,
+			`'$HOME'`,
+			"'`id`'",
+			`'back\\slash'`,
+			`'\\\''`,
+			"'line1\nline2'",
+			"'cr\r\n'",
+			`'\'"$` + "`\\\\\n'",
+		}},
+		{"powershell", powershellQuote, []string{
+			`''`,
+			`'simple'`,
+			`'it''s'`,
+			`'say "hi"'`,
+			`'$HOME'`,
+			"'`id`'",
+			`'back\slash'`,
+			`'\'''`,
+			"'line1\nline2'",
+			"'cr\r\n'",
+			`'''"$` + "`\\\n'",
+		}},
+		{"dotenv", dotenvQuote, []string{
+			`''`,
+			`'simple'`,
+			`"it's"`,
+			`'say "hi"'`,
+			`'$HOME'`,
+			"'`id`'",
+			`'back\slash'`,
+			`"\\'"`,
+			`"line1\nline2"`,
+			`"cr\r\n"`,
+			`"'\"\$` + "`\\\\\\n\"",
+		}},
+	} {
+		for i, value := range tricky {
+			if got := test.quote(value); got != test.expected[i] {
+				t.Errorf("%s: %q: got %q, expected %q", test.name, value, got, test.expected[i])
+			}
+		}
+	}
+
+	// PowerShell typographic quotes
+	if got := powershellQuote("a’b"); got != "'a’’b'" {
+		t.Errorf("powershell: got %q", got)
+	}
+}
+
+// TestShellBash checks that the bash output is read back as the original
+// value.
+func TestShellBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	assign := shells["bash"]
+	for _, value := range tricky {
+		script := assign("V", value) + "\nprintf %s \"$V\""
+		out, err := exec.Command("bash", "-c", script).Output()
+		if err != nil {
+			t.Errorf("%q: %v", value, err)
+			continue
+		}
+		if string(out) != value {
+			t.Errorf("%q: got %q", value, out)
+		}
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	for _, prefix := range []string{"", "MYSQL_", "_x1", "DB"} {
+		if err := checkPrefix(prefix); err != nil {
+			t.Errorf("%q: %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"1A", "A-B", "A B", "A;rm -rf /;", "$(id)", "A\n", strings.Repeat("é", 2)} {
+		if err := checkPrefix(prefix); err == nil {
+			t.Errorf("%q: error expected", prefix)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Command mysql-secret-store-login-path is a MySQL Shell secret store
+// helper that stores passwords in ~/.mylogin.cnf, compatible with the C++
+// helper of the same name shipped with MySQL Shell (see
+// [github.com/dolmen-go/mylogin/secretstore]).
+//
+// # Usage
+//
+//	mysql-secret-store-login-path store|get|erase|list|version
+//
+// Install it in the PATH, then configure MySQL Shell with:
+//
+//	shell.options.setPersist("credentialStore.helper", "login-path")
+//
+// The file is $MYSQL_TEST_LOGIN_FILE if set, like for the MySQL client.
+package main
+
+import (
+	"os"
+
+	"github.com/dolmen-go/mylogin/secretstore"
+)
+
+func main() {
+	os.Exit(secretstore.Run(&secretstore.Store{}, os.Args[1:], os.Stdin, os.Stdout))
+}
@@ -0,0 +1,202 @@
+package mylogin
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Flags are the command-line options of the MySQL client that define the
+// login, registered on a [flag.FlagSet] by RegisterFlags:
+//
+//	-login-path <section>
+//	-defaults-group-suffix <suffix>
+//	-u, -user <user>
+//	-p, -password[=<password>]
+//	-h, -host <host>
+//	-P, -port <port>
+//	-S, -socket <socket>
+//
+// As -p is a boolean flag (to prompt for the password), a password on the
+// command line must be given as -p=<password> or -password=<password>.
+// The value "true" also prompts for the password.
+type Flags struct {
+	LoginPath   string
+	GroupSuffix string
+	// Options given on the command line
+	Login Login
+	// promptPassword is set by -p without value
+	promptPassword bool
+
+	// Layers are the login files. Default: DefaultFile(), skipped if it
+	// doesn't exist, like the MySQL client does.
+	Layers Layers
+	// ReadPassword reads the password if -p is given without value.
+	// Default: a line read from stdin, but only if stdin isn't a terminal.
+	// Use [github.com/dolmen-go/mylogin/prompt.Password] to prompt on the
+	// terminal with echo disabled.
+	ReadPassword func(prompt string) (string, error)
+}
+
+// RegisterFlags registers the login options of the MySQL client on fs (or
+// on [flag.CommandLine] if nil). Call Flags.Resolve after parsing the
+// command line.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	f := &Flags{}
+	fs.StringVar(&f.LoginPath, "login-path", "", "read login path `section` from the login file")
+	fs.StringVar(&f.GroupSuffix, "defaults-group-suffix", "", "also read sections with this `suffix`")
+	for _, opt := range []struct {
+		short, long string
+		value       flag.Value
+		usage       string
+	}{
+		{"u", "user", stringOption{&f.Login.User}, "user for login"},
+		{"p", "password", passwordOption{f}, "password to use when connecting to server (prompt if no value)"},
+		{"h", "host", stringOption{&f.Login.Host}, "connect to host"},
+		{"P", "port", stringOption{&f.Login.Port}, "port number to use for connection"},
+		{"S", "socket", stringOption{&f.Login.Socket}, "the socket file to use for connection"},
+	} {
+		fs.Var(opt.value, opt.short, opt.usage)
+		fs.Var(opt.value, opt.long, opt.usage)
+	}
+	return f
+}
+
+// SectionNames returns the names of the sections to merge, by increasing
+// precedence: [client] and the login path, each followed by the same name
+// with the group suffix.
+func (f *Flags) SectionNames() []string {
+	groups := []string{DefaultSection}
+	if f.LoginPath != "" && f.LoginPath != DefaultSection {
+		groups = append(groups, f.LoginPath)
+	}
+	var names []string
+	for _, name := range groups {
+		names = append(names, name)
+		if f.GroupSuffix != "" {
+			names = append(names, name+f.GroupSuffix)
+		}
+	}
+	return names
+}
+
+// Resolve reads the login files (see Flags.Layers) and returns the Login
+// with the precedence of the MySQL client: command line over login path
+// over [client]. The password is read (see Flags.ReadPassword) if -p is
+// given without value.
+func (f *Flags) Resolve() (*Login, error) {
+	layers := f.Layers
+	if layers == nil {
+		layers = Layers{FileLayer(DefaultFile(), true)}
+	}
+	sections, err := layers.ReadSections()
+	if err != nil {
+		return nil, err
+	}
+	return f.ResolveSections(sections)
+}
+
+// ResolveSections is like Resolve with already read sections.
+func (f *Flags) ResolveSections(sections Sections) (*Login, error) {
+	login := sections.Merge(f.SectionNames())
+	if login == nil {
+		login = new(Login)
+	}
+	login.Merge(&f.Login)
+	if f.promptPassword {
+		readPassword := f.ReadPassword
+		if readPassword == nil {
+			readPassword = readPasswordStdin
+		}
+		password, err := readPassword("Enter password: ")
+		if err != nil {
+			return nil, err
+		}
+		login.Password = &password
+	}
+	return login, nil
+}
+
+var errTerminal = errors.New("can't read a password from a terminal without disabling echo: set Flags.ReadPassword")
+
+// readPasswordStdin is the default of Flags.ReadPassword. As disabling echo
+// is platform specific, the password is read only if stdin isn't a
+// terminal: see package [github.com/dolmen-go/mylogin/prompt] for a
+// terminal.
+func readPasswordStdin(prompt string) (string, error) {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		return "", errTerminal
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	return readLine(os.Stdin)
+}
+
+// readLine reads r up to the end of line without buffering, so nothing
+// after the line is consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	var b [1]byte
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+// stringOption is a flag.Value for an optional string.
+type stringOption struct {
+	p **string
+}
+
+func (o stringOption) String() string {
+	if o.p == nil || *o.p == nil {
+		return ""
+	}
+	return **o.p
+}
+
+func (o stringOption) Set(s string) error {
+	*o.p = &s
+	return nil
+}
+
+// passwordOption is a flag.Value for -p: a boolean flag (to prompt) that
+// also accepts a value.
+type passwordOption struct {
+	f *Flags
+}
+
+func (passwordOption) IsBoolFlag() bool { return true }
+
+func (o passwordOption) String() string {
+	// Never show the password in the usage
+	return ""
+}
+
+func (o passwordOption) Set(s string) error {
+	if s == "true" {
+		o.f.promptPassword = true
+		o.f.Login.Password = nil
+		return nil
+	}
+	o.f.promptPassword = false
+	o.f.Login.Password = &s
+	return nil
+}
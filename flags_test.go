@@ -0,0 +1,95 @@
+package mylogin_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestFlags(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen"), Host: stringPtr("localhost")}},
+		{Name: "prod", Login: mylogin.Login{Host: stringPtr("db"), Password: stringPtr("secret")}},
+		{Name: "prod_ro", Login: mylogin.Login{User: stringPtr("ro")}},
+	}
+
+	for _, test := range []struct {
+		args     []string
+		names    []string
+		expected mylogin.Login
+	}{
+		{nil, []string{"client"},
+			mylogin.Login{User: stringPtr("dolmen"), Host: stringPtr("localhost")}},
+		{[]string{"-login-path", "prod"}, []string{"client", "prod"},
+			mylogin.Login{User: stringPtr("dolmen"), Host: stringPtr("db"), Password: stringPtr("secret")}},
+		{[]string{"-login-path=prod", "-defaults-group-suffix=_ro"}, []string{"client", "client_ro", "prod", "prod_ro"},
+			mylogin.Login{User: stringPtr("ro"), Host: stringPtr("db"), Password: stringPtr("secret")}},
+		{[]string{"-login-path=prod", "-u", "admin", "-h=db2", "-P", "3307"}, []string{"client", "prod"},
+			mylogin.Login{User: stringPtr("admin"), Host: stringPtr("db2"), Port: stringPtr("3307"), Password: stringPtr("secret")}},
+		{[]string{"-login-path=prod", "-password=other", "-S", "/tmp/mysql.sock"}, []string{"client", "prod"},
+			mylogin.Login{User: stringPtr("dolmen"), Host: stringPtr("db"), Password: stringPtr("other"), Socket: stringPtr("/tmp/mysql.sock")}},
+		{[]string{"-p", "-user", "me"}, []string{"client"},
+			mylogin.Login{User: stringPtr("me"), Host: stringPtr("localhost"), Password: stringPtr("typed")}},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		f := mylogin.RegisterFlags(fs)
+		f.ReadPassword = func(prompt string) (string, error) {
+			return "typed", nil
+		}
+		if err := fs.Parse(test.args); err != nil {
+			t.Errorf("%q: %v", test.args, err)
+			continue
+		}
+		if names := f.SectionNames(); !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%q: got sections %q, expected %q", test.args, names, test.names)
+		}
+		login, err := f.ResolveSections(sections)
+		if err != nil {
+			t.Errorf("%q: %v", test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*login, test.expected) {
+			t.Errorf("%q: got %s, expected %s", test.args, login, &test.expected)
+		}
+	}
+}
+
+func TestFlagsPasswordPipe(t *testing.T) {
+	f, err := ioutil.TempFile("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err = f.WriteString("secret\r\nrest\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(0, 0)
+
+	defer func(stdin, stderr *os.File) {
+		os.Stdin, os.Stderr = stdin, stderr
+	}(os.Stdin, os.Stderr)
+	os.Stdin = f
+	os.Stderr, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer os.Stderr.Close()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := mylogin.RegisterFlags(fs)
+	if err = fs.Parse([]string{"-p"}); err != nil {
+		t.Fatal(err)
+	}
+	login, err := flags.ResolveSections(nil)
+	if err != nil || login.Password == nil || *login.Password != "secret" {
+		t.Errorf("got %v, %v", login, err)
+	}
+	// The input after the password is left unread
+	rest, _ := ioutil.ReadAll(f)
+	if string(rest) != "rest\n" {
+		t.Errorf("rest: got %q", rest)
+	}
+}
@@ -0,0 +1,4 @@
+package format
+
+// Unregister removes a format registered by a test.
+var Unregister = unregister
@@ -0,0 +1,230 @@
+// Package format provides the output formats of command mylogin and a
+// registry to extend them.
+//
+// A format is a Formatter that also implements either SectionFormatter
+// (called once for each section) or FileFormatter (called once with all
+// the sections). Formats that take an argument, such as a template,
+// implement Configurable. Formats that need the whole file, and not only the
+// selected sections, implement FileSetter.
+//
+// Custom formats are registered with Register, usually from an init
+// function:
+//
+//	func init() {
+//		format.Register(registryFormat{})
+//	}
+//
+// Lookup returns a new value for each call, so that the configuration of a
+// format (see Configurable) is not shared. Formats that have a state must
+// be registered with RegisterFunc:
+//
+//	func init() {
+//		format.RegisterFunc(func() format.Formatter { return &myTemplate{} })
+//	}
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// Formatter is an output format.
+type Formatter interface {
+	// Help returns the name of the format and a short description.
+	Help() (name string, usage string)
+}
+
+// SectionFormatter is a Formatter that formats sections one by one.
+type SectionFormatter interface {
+	Formatter
+	FormatSection(w io.Writer, section *mylogin.Section) error
+}
+
+// FileFormatter is a Formatter that needs all the sections at once.
+type FileFormatter interface {
+	Formatter
+	FormatFile(w io.Writer, sections mylogin.Sections) error
+}
+
+// FileSetter is a Formatter that needs all the sections of the file, in
+// addition to the sections to format (for example to merge [client]).
+// SetFile is called before formatting.
+type FileSetter interface {
+	Formatter
+	SetFile(sections mylogin.Sections)
+}
+
+// Configurable is a Formatter that takes an argument.
+type Configurable interface {
+	Formatter
+	// Set configures the format from an argument given on the command line.
+	Set(arg string) error
+}
+
+// registry holds the constructors of the formats.
+var registry []func() Formatter
+
+func init() {
+	Register(Replay{})
+	Register(ReplaySet{})
+	Register(Remove{})
+	Register(JSON{})
+	RegisterFunc(func() Formatter { return &Template{} })
+	RegisterFunc(func() Formatter { return &TemplateLn{} })
+	RegisterFunc(func() Formatter { return &TemplateFile{} })
+}
+
+// Register registers a stateless format: Lookup returns f itself. Use
+// RegisterFunc for a format that has a state, such as a Configurable. It
+// panics like RegisterFunc.
+func Register(f Formatter) {
+	RegisterFunc(func() Formatter { return f })
+}
+
+// RegisterFunc registers a format with a function that returns a new value
+// of the format for each call of Lookup. It panics if the format doesn't
+// implement either SectionFormatter or FileFormatter, or if a format with
+// the same name is already registered.
+func RegisterFunc(newFormat func() Formatter) {
+	f := newFormat()
+	switch f.(type) {
+	case SectionFormatter, FileFormatter:
+	default:
+		panic(fmt.Sprintf("format: %T implements neither SectionFormatter nor FileFormatter", f))
+	}
+	name, _ := f.Help()
+	if Lookup(name) != nil {
+		panic("format: duplicate format " + name)
+	}
+	registry = append(registry, newFormat)
+}
+
+// unregister removes a format, for tests.
+func unregister(name string) {
+	for i, newFormat := range registry {
+		if n, _ := newFormat().Help(); n == name {
+			registry = append(registry[:i:i], registry[i+1:]...)
+			return
+		}
+	}
+}
+
+// Lookup returns a new value of the registered format with the given name,
+// or nil.
+func Lookup(name string) Formatter {
+	for _, newFormat := range registry {
+		f := newFormat()
+		if n, _ := f.Help(); n == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Formatters returns new values of the registered formats, in the order of
+// registration.
+func Formatters() []Formatter {
+	formats := make([]Formatter, len(registry))
+	for i, newFormat := range registry {
+		formats[i] = newFormat()
+	}
+	return formats
+}
+
+// Names returns the names of the registered formats, in the order of
+// registration.
+func Names() []string {
+	names := make([]string, len(registry))
+	for i, newFormat := range registry {
+		names[i], _ = newFormat().Help()
+	}
+	return names
+}
+
+// Format writes sections to w using f.
+func Format(w io.Writer, f Formatter, sections mylogin.Sections) error {
+	switch f := f.(type) {
+	case FileFormatter:
+		return f.FormatFile(w, sections)
+	case SectionFormatter:
+		for i := range sections {
+			if err := f.FormatSection(w, &sections[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		name, _ := f.Help()
+		return fmt.Errorf("format %s: no Format method", name)
+	}
+}
+
+// LoginAsMap converts a Login to a map with keys user, password, host,
+// socket and port, skipping options that are not set.
+func LoginAsMap(login *mylogin.Login) map[string]interface{} {
+	// The login struct contains *string
+	// This is not convenient to use in templates
+	// So we remap it to a map, skipping nil values
+	m := make(map[string]interface{})
+	for _, x := range []struct {
+		key   string
+		value *string
+	}{
+		{"user", login.User},
+		{"password", login.Password},
+		{"host", login.Host},
+		{"socket", login.Socket},
+		{"port", login.Port},
+	} {
+		if x.value != nil {
+			m[x.key] = *x.value
+		}
+	}
+
+	return m
+}
+
+// MapAsLogin is the reverse of LoginAsMap.
+func MapAsLogin(m map[string]interface{}) *mylogin.Login {
+	get := func(key string) *string {
+		if v, ok := m[key].(string); ok {
+			return &v
+		}
+		return nil
+	}
+	return &mylogin.Login{
+		User:     get("user"),
+		Password: get("password"),
+		Host:     get("host"),
+		Port:     get("port"),
+		Socket:   get("socket"),
+	}
+}
+
+// SectionAsMap is LoginAsMap with the additional keys section (the name
+// of the section) and groupSuffix.
+func SectionAsMap(section *mylogin.Section) map[string]interface{} {
+	m := LoginAsMap(&section.Login)
+
+	m["section"] = section.Name
+	// Export trucated section name to use with --defaults-group-suffix option of the MySQL CLI
+	const sectionSuffix = "groupSuffix"
+	switch {
+	case strings.HasPrefix(section.Name, "mysql"):
+		m[sectionSuffix] = section.Name[5:]
+	case strings.HasPrefix(section.Name, "client"):
+		m[sectionSuffix] = section.Name[6:]
+	}
+	return m
+}
+
+// ShQuote quotes s for POSIX shells.
+func ShQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@%+=,") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,111 @@
+package format_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/format"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+type names struct{}
+
+func (names) Help() (string, string) {
+	return "test-names", "section names on one line"
+}
+
+func (names) FormatFile(w io.Writer, sections mylogin.Sections) error {
+	for _, s := range sections {
+		io.WriteString(w, s.Name+" ")
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func TestRegister(t *testing.T) {
+	format.Register(names{})
+	defer format.Unregister("test-names")
+	f := format.Lookup("test-names")
+	if f == nil {
+		t.Fatal("format not registered")
+	}
+
+	var b bytes.Buffer
+	err := format.Format(&b, f, mylogin.Sections{{Name: "a"}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "a b \n" {
+		t.Errorf("got %q", got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic expected for duplicate format")
+			}
+		}()
+		format.Register(names{})
+	}()
+}
+
+func TestFormatSection(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("o'neil"), Password: stringPtr("x")}},
+		{Name: "my prod", Login: mylogin.Login{Host: stringPtr("db")}},
+	}
+	tmpl := format.Lookup("templateln").(format.Configurable)
+	if err := tmpl.Set(`{{.section}}|{{.groupSuffix}}|{{json .user}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		format   format.Formatter
+		expected string
+	}{
+		{format.Lookup("replay"), `mysql_config_editor set --skip-warn -G client -u 'o'\''neil' -p` + "\n" +
+			`mysql_config_editor set --skip-warn -G 'my prod' -h db` + "\n"},
+		{format.Lookup("replay-set"), `mylogin set -G client '-u=o'\''neil' -p=x` + "\n" +
+			`mylogin set -G 'my prod' -h=db` + "\n"},
+		{tmpl, "client||\"o'neil\"\nmy prod|<no value>|null\n"},
+	} {
+		var b bytes.Buffer
+		if err := format.Format(&b, test.format, sections); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != test.expected {
+			name, _ := test.format.Help()
+			t.Errorf("%s: got %q, expected %q", name, got, test.expected)
+		}
+	}
+}
+
+func TestLookupNew(t *testing.T) {
+	// Each Lookup returns a new value: configuring one doesn't change the
+	// others
+	tmpl1 := format.Lookup("template").(format.Configurable)
+	if err := tmpl1.Set("1"); err != nil {
+		t.Fatal(err)
+	}
+	tmpl2 := format.Lookup("template").(format.Configurable)
+
+	sections := mylogin.Sections{{Name: "a"}}
+	var b bytes.Buffer
+	if err := format.Format(&b, tmpl1, sections); err != nil || b.String() != "1" {
+		t.Errorf("got %q, %v", b.String(), err)
+	}
+	// Not set: error, not panic
+	for _, name := range []string{"template", "templateln", "template-file"} {
+		if err := format.Format(&b, format.Lookup(name), sections); err == nil {
+			t.Errorf("%s: error expected without template", name)
+		}
+	}
+	if err := format.Format(&b, tmpl2, sections); err == nil {
+		t.Error("error expected without template")
+	}
+}
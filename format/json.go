@@ -0,0 +1,22 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// JSON is the JSON format of the Login of each section.
+type JSON struct{}
+
+func (JSON) Help() (string, string) {
+	return "json", "JSON format (note: section name is not exported)"
+}
+
+func (JSON) FormatSection(w io.Writer, section *mylogin.Section) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(LoginAsMap(&section.Login))
+}
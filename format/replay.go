@@ -0,0 +1,93 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// Replay is the format of mysql_config_editor 'set' commands.
+type Replay struct{}
+
+func (Replay) Help() (string, string) {
+	return "replay", "mysql_config_editor 'set' command format (note: password is not exported)"
+}
+
+func (Replay) FormatSection(w io.Writer, section *mylogin.Section) error {
+	args := make([]string, 5, 5+5*2)
+	args[0] = `mysql_config_editor`
+	args[1] = `set`
+	args[2] = `--skip-warn`
+	args[3] = `-G`
+	args[4] = section.Name
+	if section.Login.User != nil {
+		args = append(args, `-u`, *section.Login.User)
+	}
+	if section.Login.Password != nil {
+		args = append(args, `-p`)
+	}
+	if section.Login.Host != nil {
+		args = append(args, `-h`, *section.Login.Host)
+	}
+	if section.Login.Port != nil {
+		args = append(args, `-P`, *section.Login.Port)
+	}
+	if section.Login.Socket != nil {
+		args = append(args, `-S`, *section.Login.Socket)
+	}
+	return printCommand(w, args)
+}
+
+// ReplaySet is the format of 'mylogin set' commands, including passwords.
+type ReplaySet struct{}
+
+func (ReplaySet) Help() (string, string) {
+	return "replay-set", "'mylogin set' command format (note: password is exported)"
+}
+
+func (ReplaySet) FormatSection(w io.Writer, section *mylogin.Section) error {
+	args := make([]string, 4, 4+5*2)
+	args[0] = `mylogin`
+	args[1] = `set`
+	args[2] = `-G`
+	args[3] = section.Name
+	for _, opt := range []struct {
+		flag  string
+		value *string
+	}{
+		{`-u`, section.Login.User},
+		{`-p`, section.Login.Password},
+		{`-h`, section.Login.Host},
+		{`-P`, section.Login.Port},
+		{`-S`, section.Login.Socket},
+	} {
+		if opt.value != nil {
+			// The '=' form allows empty values
+			args = append(args, opt.flag+"="+*opt.value)
+		}
+	}
+	return printCommand(w, args)
+}
+
+// printCommand prints a command line quoted for POSIX shells.
+func printCommand(w io.Writer, args []string) error {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShQuote(arg)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(quoted, " "))
+	return err
+}
+
+// Remove is the format of mysql_config_editor 'remove' commands.
+type Remove struct{}
+
+func (Remove) Help() (string, string) {
+	return "remove", "mysql_config_editor 'remove' command format"
+}
+
+func (Remove) FormatSection(w io.Writer, section *mylogin.Section) error {
+	return printCommand(w, []string{`mysql_config_editor`, `remove`, `-G`, section.Name})
+}
@@ -0,0 +1,187 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// errNoTemplate is returned by the template formats if Set hasn't been
+// called.
+var errNoTemplate = errors.New("format: no template set")
+
+// Template executes a text/template for each section, with the result of
+// SectionAsMap as data.
+type Template struct {
+	tmpl *template.Template
+}
+
+func (*Template) Help() (string, string) {
+	return "template", "text/template format (additional function: 'json')"
+}
+
+func (f *Template) Set(s string) error {
+	tmpl, err := template.New("user-template").Funcs(
+		template.FuncMap{
+			"json": templateJSON,
+		},
+	).Parse(s)
+	if err != nil {
+		return err
+	}
+	f.tmpl = tmpl
+	return nil
+}
+
+func (f *Template) FormatSection(w io.Writer, section *mylogin.Section) error {
+	if f.tmpl == nil {
+		return errNoTemplate
+	}
+	return f.tmpl.Execute(w, SectionAsMap(section))
+}
+
+// TemplateLn is Template with a trailing line break.
+type TemplateLn struct {
+	Template
+}
+
+func (*TemplateLn) Help() (string, string) {
+	return "templateln", "text/template format (additional function: 'json') with trailing line break"
+}
+
+func (f *TemplateLn) Set(s string) error {
+	return f.Template.Set(s + "\n")
+}
+
+// TemplateFile executes a template read from a file once, with the list of
+// the sections to format as data (each converted with SectionAsMap). The
+// 'merge' function merges the sections of the whole file (see SetFile).
+type TemplateFile struct {
+	tmpl     *template.Template
+	file     mylogin.Sections // For the 'merge' function
+	sections mylogin.Sections // The sections to format
+}
+
+func (*TemplateFile) Help() (string, string) {
+	return "template-file", "text/template `file` executed with the list of the selected sections (additional functions: 'json', 'dsn', 'uri', 'shquote', 'redact', 'merge')"
+}
+
+// SetFile sets the sections used by 'merge'. If not set, 'merge' uses the
+// sections to format.
+func (f *TemplateFile) SetFile(sections mylogin.Sections) {
+	f.file = sections
+}
+
+// Set reads the template from filename.
+func (f *TemplateFile) Set(filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(filepath.Base(filename)).Funcs(template.FuncMap{
+		"json": templateJSON,
+		"dsn": func(entry map[string]interface{}) string {
+			return MapAsLogin(entry).DSN()
+		},
+		"uri":     templateURI,
+		"shquote": ShQuote,
+		"redact":  templateRedact,
+		"merge": func(names ...string) map[string]interface{} {
+			file := f.file
+			if file == nil {
+				file = f.sections
+			}
+			login := file.Merge(names)
+			if login == nil {
+				login = new(mylogin.Login)
+			}
+			return LoginAsMap(login)
+		},
+	}).Parse(string(content))
+	if err != nil {
+		return err
+	}
+	f.tmpl = tmpl
+	return nil
+}
+
+func (f *TemplateFile) FormatFile(w io.Writer, sections mylogin.Sections) error {
+	data := make([]map[string]interface{}, len(sections))
+	for i := range sections {
+		data[i] = SectionAsMap(&sections[i])
+	}
+	if f.tmpl == nil {
+		return errNoTemplate
+	}
+	f.sections = sections
+	return f.tmpl.Execute(w, data)
+}
+
+func templateJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// templateURI returns a mysql:// URI for an entry.
+func templateURI(entry map[string]interface{}) string {
+	login := MapAsLogin(entry)
+	var b strings.Builder
+	b.WriteString("mysql://")
+	if login.User != nil {
+		if login.Password != nil {
+			b.WriteString(url.UserPassword(*login.User, *login.Password).String())
+		} else {
+			b.WriteString(url.User(*login.User).String())
+		}
+		b.WriteByte('@')
+	}
+	switch {
+	case login.Socket != nil:
+		b.WriteString(url.PathEscape(*login.Socket))
+	case login.Host != nil && login.Port != nil:
+		b.WriteString(net.JoinHostPort(*login.Host, *login.Port))
+	case login.Host != nil:
+		if strings.IndexByte(*login.Host, ':') >= 0 {
+			b.WriteString("[" + *login.Host + "]")
+		} else {
+			b.WriteString(*login.Host)
+		}
+	case login.Port != nil:
+		b.WriteString(net.JoinHostPort("localhost", *login.Port))
+	}
+	return b.String()
+}
+
+// templateRedact replaces a password, or the password of an entry.
+func templateRedact(v interface{}) (interface{}, error) {
+	const redacted = "********"
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return redacted, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		if _, ok := m["password"]; ok {
+			m["password"] = redacted
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("redact: unexpected type %T", v)
+	}
+}
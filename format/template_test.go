@@ -0,0 +1,93 @@
+package format_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/format"
+)
+
+func TestTemplateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("me"), Password: stringPtr("p@ss word")}},
+		{Name: "prod", Login: mylogin.Login{Host: stringPtr("db"), Port: stringPtr("3307")}},
+		{Name: "local", Login: mylogin.Login{User: stringPtr("root"), Socket: stringPtr("/tmp/mysql.sock")}},
+		{Name: "v6", Login: mylogin.Login{User: stringPtr("o'neil"), Host: stringPtr("::1")}},
+	}
+
+	for _, test := range []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"range", `{{range .}}{{.section}};{{end}}`, "client;prod;local;v6;"},
+		{"json", `{{json (index . 1)}}`, `{"host":"db","port":"3307","section":"prod"}`},
+		{"dsn", `{{range .}}{{dsn .}} {{end}}`,
+			"me:p@ss word@/ tcp(db:3307)/ root@unix(/tmp/mysql.sock)/ o'neil@tcp([::1]:3306)/ "},
+		{"uri", `{{range .}}{{uri .}} {{end}}`,
+			"mysql://me:p%40ss%20word@ mysql://db:3307 mysql://root@%2Ftmp%2Fmysql.sock mysql://o%27neil@[::1] "},
+		{"shquote", `{{range .}}{{with .user}}{{shquote .}}{{end}}|{{shquote .section}} {{end}}`, `me|client |prod root|local 'o'\''neil'|v6 `},
+		{"redact password", `{{range .}}[{{redact .password}}]{{end}}`, "[********][][][]"},
+		{"redact entry", `{{range .}}{{uri (redact .)}} {{end}}`,
+			"mysql://me:%2A%2A%2A%2A%2A%2A%2A%2A@ mysql://db:3307 mysql://root@%2Ftmp%2Fmysql.sock mysql://o%27neil@[::1] "},
+		{"merge", `{{with merge "client" "prod"}}{{.user}}@{{.host}}:{{.port}}{{end}}|{{len (merge "none")}}`, "me@db:3307|0"},
+	} {
+		filename := filepath.Join(dir, test.name+".tmpl")
+		if err := ioutil.WriteFile(filename, []byte(test.template), 0600); err != nil {
+			t.Fatal(err)
+		}
+		f := &format.TemplateFile{}
+		if err := f.Set(filename); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var b bytes.Buffer
+		if err := format.Format(&b, f, sections); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := b.String(); got != test.expected {
+			t.Errorf("%s: got %q, expected %q", test.name, got, test.expected)
+		}
+	}
+
+	// 'merge' uses the whole file, the data is only the selection
+	filename := filepath.Join(dir, "selection.tmpl")
+	ioutil.WriteFile(filename, []byte(`{{range .}}{{.section}}{{end}}:{{with merge "client" "prod"}}{{.user}}@{{.host}}{{end}}`), 0600)
+	f := &format.TemplateFile{}
+	if err := f.Set(filename); err != nil {
+		t.Fatal(err)
+	}
+	f.SetFile(sections)
+	var b bytes.Buffer
+	if err := format.Format(&b, f, sections[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "prod:me@db" {
+		t.Errorf("selection: got %q", got)
+	}
+
+	// Errors
+	bad := filepath.Join(dir, "bad.tmpl")
+	ioutil.WriteFile(bad, []byte(`{{redact 1}}`), 0600)
+	f = &format.TemplateFile{}
+	if err := f.Set(bad); err != nil {
+		t.Fatal(err)
+	}
+	if err := format.Format(ioutil.Discard, f, sections); err == nil {
+		t.Error("redact of an int: error expected")
+	}
+	if err := f.Set(filepath.Join(dir, "missing.tmpl")); err == nil {
+		t.Error("missing file: error expected")
+	}
+}
@@ -0,0 +1,49 @@
+//go:build go1.16
+// +build go1.16
+
+package mylogin
+
+import "io/fs"
+
+// ReadSectionsFS reads all Sections of the mylogin.cnf file name in fsys,
+// such as an [embed.FS].
+//
+// Unlike ReadSections, the permissions of the file are not checked.
+func ReadSectionsFS(fsys fs.FS, name string) (Sections, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSectionsFrom(f)
+}
+
+// ReadLoginFS reads the mylogin.cnf file name in fsys, extracts the
+// requested sections and merges them to obtain a single Login (that may be
+// empty). See ReadLogin.
+//
+// Unlike ReadLogin, the permissions of the file are not checked.
+func ReadLoginFS(fsys fs.FS, name string, sectionNames []string) (*Login, error) {
+	sections, err := ReadSectionsFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return sections.Merge(sectionNames), nil
+}
+
+// FSLayer returns a Layer for the mylogin.cnf file name in fsys.
+// Unlike FileLayer, the permissions of the file are not checked.
+func FSLayer(fsys fs.FS, name string, optional bool) Layer {
+	return Layer{
+		Name:     name,
+		Optional: optional,
+		read: func() (*Source, error) {
+			f, err := fsys.Open(name)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return readSource(name, f)
+		},
+	}
+}
@@ -0,0 +1,70 @@
+//go:build go1.16
+// +build go1.16
+
+package mylogin_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestReadSectionsFS(t *testing.T) {
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen")}},
+		{Name: "prod", Login: mylogin.Login{Host: stringPtr("db")}},
+	}
+	var b bytes.Buffer
+	if err = mylogin.Encode(&b, mylogin.NewFile(key, nil, sections)); err != nil {
+		t.Fatal(err)
+	}
+	// Mode 0644 would be rejected by ReadSections
+	fsys := fstest.MapFS{"dir/mylogin.cnf": {Data: b.Bytes(), Mode: 0644}}
+
+	got, err := mylogin.ReadSectionsFS(fsys, "dir/mylogin.cnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sections) {
+		t.Errorf("got %#v, expected %#v", got, sections)
+	}
+
+	got, err = mylogin.ReadSectionsFrom(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sections) {
+		t.Errorf("ReadSectionsFrom: got %#v, expected %#v", got, sections)
+	}
+
+	login, err := mylogin.ReadLoginFS(fsys, "dir/mylogin.cnf", []string{"client", "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn := login.DSN(); dsn != "dolmen@tcp(db:3306)/" {
+		t.Errorf("ReadLoginFS: got %s", dsn)
+	}
+
+	if _, err = mylogin.ReadSectionsFS(fsys, "missing.cnf"); err == nil {
+		t.Error("error expected")
+	}
+
+	layers := mylogin.Layers{
+		mylogin.FSLayer(fsys, "missing.cnf", true),
+		mylogin.FSLayer(fsys, "dir/mylogin.cnf", false),
+	}
+	if login, err = layers.ReadLogin([]string{"prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if dsn := login.DSN(); dsn != "tcp(db:3306)/" {
+		t.Errorf("FSLayer: got %s", dsn)
+	}
+}
@@ -2,4 +2,8 @@ module github.com/dolmen-go/mylogin
 
 go 1.12
 
-require github.com/go-sql-driver/mysql v1.4.0
+require (
+	github.com/go-sql-driver/mysql v1.4.0
+	golang.org/x/sys v0.7.0
+	golang.org/x/term v0.0.0-20210422114643-f5beecf764ed
+)
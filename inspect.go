@@ -0,0 +1,148 @@
+package mylogin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Inspection is the binary structure of a mylogin.cnf file, for debugging.
+// It doesn't expose the plaintext content of the file, except section and
+// option names.
+type Inspection struct {
+	Header    [4]byte     `json:"header"`
+	Key       Key         `json:"key"`
+	ByteOrder string      `json:"byteOrder"` // "little-endian" or "big-endian"
+	Chunks    []ChunkInfo `json:"chunks"`
+	// Size is the number of bytes read.
+	Size int64 `json:"size"`
+	// End is empty if the file ends cleanly after the last chunk, or
+	// describes the error that stopped the decoding (such as a truncated
+	// trailing chunk).
+	End string `json:"end,omitempty"`
+	// Anomalies lists what mysql_config_editor would not have written at
+	// the file level. See also ChunkInfo.Anomalies.
+	Anomalies []string `json:"anomalies,omitempty"`
+}
+
+// ChunkInfo describes an encrypted chunk. Each chunk holds a line of the
+// plaintext.
+type ChunkInfo struct {
+	Offset       int64  `json:"offset"` // Offset of the chunk size
+	Size         int    `json:"size"`   // Size of the encrypted data
+	Padding      int    `json:"padding"`
+	PaddingValid bool   `json:"paddingValid"`
+	Length       int    `json:"length"` // Length of the plaintext
+	Kind         string `json:"kind"`   // "section", "option", "empty" or "invalid"
+	// Name is the section name or the option name.
+	Name      string   `json:"name,omitempty"`
+	Anomalies []string `json:"anomalies,omitempty"`
+}
+
+// IsClean is true if neither the file nor any chunk has anomalies.
+func (insp *Inspection) IsClean() bool {
+	if insp.End != "" || len(insp.Anomalies) > 0 {
+		return false
+	}
+	for i := range insp.Chunks {
+		if len(insp.Chunks[i].Anomalies) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Inspect decodes a mylogin.cnf file and reports its binary structure.
+// An error is returned only if the header can't be read.
+func Inspect(r io.Reader) (*Inspection, error) {
+	f, err := Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	d := f.(*decoder)
+
+	insp := &Inspection{
+		Header: d.header,
+		Key:    d.key,
+	}
+	if d.byteOrder == binary.BigEndian {
+		insp.ByteOrder = "big-endian"
+	} else {
+		insp.ByteOrder = "little-endian"
+	}
+	if d.header != [4]byte{} {
+		insp.Anomalies = append(insp.Anomalies, fmt.Sprintf("header is not zero: % X", d.header[:]))
+	}
+	if err := d.key.Validate(); err != nil {
+		insp.Anomalies = append(insp.Anomalies, err.Error())
+	}
+
+	var inSection bool
+	for {
+		offset := d.offset
+		size, err := d.readChunk()
+		if err != nil {
+			insp.Size = d.offset
+			if err != io.EOF {
+				insp.End = fmt.Sprintf("offset %d: %v", offset, err)
+			}
+			break
+		}
+		c := inspectChunk(d.chunk[:size], &inSection)
+		c.Offset = offset
+		insp.Chunks = append(insp.Chunks, c)
+	}
+	return insp, nil
+}
+
+func inspectChunk(chunk []byte, inSection *bool) ChunkInfo {
+	c := ChunkInfo{Size: len(chunk)}
+	plain, padding := unpad(chunk)
+	c.Padding = padding
+	c.PaddingValid = padding > 0
+	c.Length = len(plain)
+
+	if len(chunk) == 0 {
+		c.Kind = "empty"
+		c.Anomalies = append(c.Anomalies, "empty chunk")
+		return c
+	}
+	if !c.PaddingValid {
+		c.Anomalies = append(c.Anomalies, "invalid padding")
+	}
+
+	if len(plain) == 0 || plain[len(plain)-1] != '\n' {
+		c.Anomalies = append(c.Anomalies, "no line break at end of chunk")
+	} else {
+		plain = plain[:len(plain)-1]
+	}
+	if bytes.IndexByte(plain, '\n') >= 0 {
+		c.Anomalies = append(c.Anomalies, "multiple lines in chunk")
+	}
+
+	line := string(plain)
+	switch {
+	case line == "":
+		c.Kind = "empty"
+	case line[0] == '[' && line[len(line)-1] == ']':
+		c.Kind = "section"
+		c.Name = line[1 : len(line)-1]
+		*inSection = true
+	case strings.Contains(line, " = "):
+		c.Kind = "option"
+		c.Name = line[:strings.Index(line, " = ")]
+		var l Login
+		if err := l.parseLine(line); err != nil {
+			c.Kind = "invalid"
+			c.Anomalies = append(c.Anomalies, err.Error())
+		} else if !*inSection {
+			c.Anomalies = append(c.Anomalies, "option outside of a section")
+		}
+	default:
+		c.Kind = "invalid"
+		c.Anomalies = append(c.Anomalies, "neither a section nor an option")
+	}
+	return c
+}
@@ -0,0 +1,90 @@
+package mylogin_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestInspectTestdata(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.cnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range files {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		insp, err := mylogin.Inspect(bytes.NewReader(content))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if !insp.IsClean() {
+			t.Errorf("%s: %+v", path, insp)
+		}
+		if insp.Size != int64(len(content)) {
+			t.Errorf("%s: size %d, expected %d", path, insp.Size, len(content))
+		}
+		if len(insp.Chunks) == 0 || insp.Chunks[0].Kind != "section" {
+			t.Errorf("%s: %+v", path, insp.Chunks)
+		}
+	}
+}
+
+func TestInspectCorrupted(t *testing.T) {
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = mylogin.Encode(&b, mylogin.NewFile(key, nil, mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen"), Password: stringPtr("secret")}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := b.Bytes()
+
+	insp, err := mylogin.Inspect(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !insp.IsClean() || len(insp.Chunks) != 3 {
+		t.Fatalf("%+v", insp)
+	}
+	for i, kind := range []string{"section", "option", "option"} {
+		if c := insp.Chunks[i]; c.Kind != kind || !c.PaddingValid {
+			t.Errorf("chunk %d: %+v", i, c)
+		}
+	}
+	if insp.Chunks[2].Name != "password" {
+		t.Errorf("chunk 2: %+v", insp.Chunks[2])
+	}
+
+	// Truncated
+	insp, err = mylogin.Inspect(bytes.NewReader(content[:len(content)-5]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insp.End == "" || len(insp.Chunks) != 2 {
+		t.Errorf("truncated: %+v", insp)
+	}
+	t.Log(insp.End)
+
+	// Non-zero header
+	corrupted := append([]byte(nil), content...)
+	corrupted[1] = 1
+	insp, err = mylogin.Inspect(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insp.Anomalies) != 1 || insp.IsClean() {
+		t.Errorf("header: %+v", insp)
+	}
+}
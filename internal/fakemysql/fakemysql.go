@@ -0,0 +1,385 @@
+// Package fakemysql is an in-process fake MySQL server for tests. It
+// implements just enough of the protocol for github.com/go-sql-driver/mysql
+// to connect with mysql_native_password (optionally over TLS) and run simple
+// queries.
+package fakemysql
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// Capability flags
+const (
+	clientLongPassword               = 1 << 0
+	clientLongFlag                   = 1 << 2
+	clientConnectWithDB              = 1 << 3
+	clientProtocol41                 = 1 << 9
+	clientSSL                        = 1 << 11
+	clientTransactions               = 1 << 13
+	clientSecureConn                 = 1 << 15
+	clientPluginAuth                 = 1 << 19
+	clientPluginAuthLenEncClientData = 1 << 21
+)
+
+// Commands
+const (
+	comQuit  = 0x01
+	comQuery = 0x03
+	comPing  = 0x0e
+)
+
+// Error is an error returned to the client.
+type Error struct {
+	Code     uint16
+	SQLState string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Server is a fake MySQL server listening on 127.0.0.1.
+type Server struct {
+	// TLS, if not nil, enables TLS. It must be set before Start.
+	TLS *tls.Config
+	// Query, if not nil, handles the queries other than "SELECT 1". It returns
+	// the rows of a single column result, or nil for an OK packet. It must be
+	// set before Start.
+	Query func(user, query string) ([]string, error)
+
+	listener net.Listener
+	mu       sync.Mutex
+	users    map[string]string
+	wg       sync.WaitGroup
+}
+
+// New returns a server that accepts the given users (name => password).
+// Set the TLS and Query fields, then call Start.
+func New(users map[string]string) *Server {
+	s := &Server{users: make(map[string]string, len(users))}
+	for user, password := range users {
+		s.users[user] = password
+	}
+	return s
+}
+
+// Start starts listening.
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	s.wg.Add(1)
+	go s.serve()
+	return nil
+}
+
+// Addr returns the host:port of the server.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and waits for the connections to end.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Password returns the current password of user.
+func (s *Server) Password(user string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	password, ok := s.users[user]
+	return password, ok
+}
+
+// SetPassword changes the password of user.
+func (s *Server) SetPassword(user, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user] = password
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			c := &serverConn{server: s, conn: conn, r: bufio.NewReader(conn)}
+			c.run()
+		}()
+	}
+}
+
+// bufferedConn is a net.Conn that reads through a bufio.Reader.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+type serverConn struct {
+	server *Server
+	conn   net.Conn
+	r      *bufio.Reader
+	seq    byte
+	user   string
+}
+
+func (c *serverConn) readPacket() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	c.seq = header[3] + 1
+	data := make([]byte, size)
+	_, err := io.ReadFull(c.r, data)
+	return data, err
+}
+
+func (c *serverConn) writePacket(data []byte) error {
+	header := []byte{byte(len(data)), byte(len(data) >> 8), byte(len(data) >> 16), c.seq}
+	c.seq++
+	_, err := c.conn.Write(append(header, data...))
+	return err
+}
+
+func (c *serverConn) writeOK() error {
+	return c.writePacket([]byte{0x00, 0, 0, 2, 0, 0, 0})
+}
+
+func (c *serverConn) writeEOF() error {
+	return c.writePacket([]byte{0xfe, 0, 0, 2, 0})
+}
+
+func (c *serverConn) writeError(err error) error {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Code: 1105, SQLState: "HY000", Message: err.Error()}
+	}
+	data := []byte{0xff, byte(e.Code), byte(e.Code >> 8), '#'}
+	data = append(data, e.SQLState...)
+	data = append(data, e.Message...)
+	return c.writePacket(data)
+}
+
+func appendLenEncString(b []byte, s string) []byte {
+	// Only short strings are supported
+	return append(append(b, byte(len(s))), s...)
+}
+
+func (c *serverConn) writeRows(rows []string) error {
+	if err := c.writePacket([]byte{1}); err != nil {
+		return err
+	}
+	col := []byte{}
+	for _, s := range []string{"def", "", "", "", "1", ""} {
+		col = appendLenEncString(col, s)
+	}
+	col = append(col, 0x0c, 33, 0, 255, 0, 0, 0, 0xfd /* VAR_STRING */, 0, 0, 0, 0, 0)
+	if err := c.writePacket(col); err != nil {
+		return err
+	}
+	if err := c.writeEOF(); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := c.writePacket(appendLenEncString(nil, row)); err != nil {
+			return err
+		}
+	}
+	return c.writeEOF()
+}
+
+func (c *serverConn) run() {
+	if err := c.handshake(); err != nil {
+		return
+	}
+	for {
+		data, err := c.readPacket()
+		if err != nil || len(data) == 0 {
+			return
+		}
+		switch data[0] {
+		case comQuit:
+			return
+		case comPing:
+			err = c.writeOK()
+		case comQuery:
+			err = c.query(string(data[1:]))
+		default:
+			err = c.writeError(&Error{Code: 1047, SQLState: "08S01", Message: "Unknown command"})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *serverConn) query(query string) error {
+	if query == "SELECT 1" {
+		return c.writeRows([]string{"1"})
+	}
+	if c.server.Query == nil {
+		return c.writeError(&Error{Code: 1064, SQLState: "42000", Message: "unsupported query"})
+	}
+	rows, err := c.server.Query(c.user, query)
+	switch {
+	case err != nil:
+		return c.writeError(err)
+	case rows == nil:
+		return c.writeOK()
+	default:
+		return c.writeRows(rows)
+	}
+}
+
+func (c *serverConn) handshake() error {
+	scramble := make([]byte, 20)
+	if _, err := rand.Read(scramble); err != nil {
+		return err
+	}
+	// Avoid NUL bytes in the scramble
+	for i := range scramble {
+		scramble[i] = scramble[i]&0x7f | 1
+	}
+	flags := uint32(clientLongPassword | clientLongFlag | clientConnectWithDB | clientProtocol41 |
+		clientTransactions | clientSecureConn | clientPluginAuth | clientPluginAuthLenEncClientData)
+	if c.server.TLS != nil {
+		flags |= clientSSL
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(10) // Protocol version
+	b.WriteString("8.0.0-fake\x00")
+	b.Write([]byte{1, 0, 0, 0}) // Connection id
+	b.Write(scramble[:8])
+	b.WriteByte(0)
+	binary.Write(&b, binary.LittleEndian, uint16(flags))
+	b.WriteByte(33)       // utf8_general_ci
+	b.Write([]byte{2, 0}) // Status
+	binary.Write(&b, binary.LittleEndian, uint16(flags>>16))
+	b.WriteByte(21)
+	b.Write(make([]byte, 10))
+	b.Write(scramble[8:])
+	b.WriteByte(0)
+	b.WriteString("mysql_native_password\x00")
+	c.seq = 0
+	if err := c.writePacket(b.Bytes()); err != nil {
+		return err
+	}
+
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(data) < 32 {
+		return errors.New("short handshake response")
+	}
+	clientFlags := binary.LittleEndian.Uint32(data)
+	if clientFlags&clientSSL != 0 {
+		if c.server.TLS == nil {
+			return errors.New("TLS not enabled")
+		}
+		// The ClientHello may already be buffered in c.r
+		tlsConn := tls.Server(bufferedConn{c.conn, c.r}, c.server.TLS)
+		if err = tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+		if data, err = c.readPacket(); err != nil {
+			return err
+		}
+	}
+
+	data = data[32:]
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return errors.New("malformed handshake response")
+	}
+	c.user = string(data[:end])
+	data = data[end+1:]
+	if len(data) == 0 || int(data[0]) > len(data)-1 {
+		return errors.New("malformed handshake response")
+	}
+	authResp := data[1 : 1+data[0]]
+
+	password, ok := c.server.Password(c.user)
+	if !ok || !bytes.Equal(authResp, scramblePassword(scramble, password)) {
+		err = &Error{Code: 1045, SQLState: "28000", Message: "Access denied for user '" + c.user + "'"}
+		c.writeError(err)
+		return err
+	}
+	return c.writeOK()
+}
+
+// scramblePassword computes the mysql_native_password response:
+// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password))).
+func scramblePassword(scramble []byte, password string) []byte {
+	if password == "" {
+		return []byte{}
+	}
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(stage2[:])
+	result := h.Sum(nil)
+	for i := range result {
+		result[i] ^= stage1[i]
+	}
+	return result
+}
+
+// SelfSignedTLS returns a server TLS configuration with a self-signed
+// certificate for host.
+func SelfSignedTLS(host string) (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, nil
+}
@@ -0,0 +1,120 @@
+package mylogin
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Keys generated by mysql_config_editor (and by NewKey) use only the 5 low
+// bits of each byte. The compact text form packs those 5 bits of each of
+// the 20 bytes into 100 bits, which are encoded either as 25 hexadecimal
+// digits or as 17 characters of the URL-safe base64 alphabet.
+// See also cmd/mylogin-key.
+const (
+	keyBits           = 5
+	compactKeyLen     = (len(Key{})*keyBits + 7) / 8 // 13 bytes
+	compactKeyHexLen  = (len(Key{})*keyBits + 3) / 4 // 25 hex digits
+	compactKeyBase64n = (len(Key{})*keyBits + 5) / 6 // 17 base64 chars
+)
+
+// Validate checks that k is not zero and that each byte uses only the 5
+// low bits, like the keys generated by mysql_config_editor.
+func (k Key) Validate() error {
+	if k.IsZero() {
+		return errors.New("key is not initialized")
+	}
+	for i, b := range k {
+		if b >= 1<<keyBits {
+			return fmt.Errorf("key byte #%d (%#02x) has more than %d significant bits", i, b, keyBits)
+		}
+	}
+	return nil
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+//
+// A key that follows the 5 bits convention (see Validate) is encoded in the
+// compact form as 25 hexadecimal digits. Other keys are encoded as 40
+// hexadecimal digits.
+func (k Key) MarshalText() ([]byte, error) {
+	if k.Validate() != nil {
+		return []byte(strings.ToUpper(hex.EncodeToString(k[:]))), nil
+	}
+	compact := k.compact()
+	text := []byte(strings.ToUpper(hex.EncodeToString(compact[:])))
+	return text[:compactKeyHexLen], nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+//
+// It accepts the compact form as 25 hexadecimal digits or as 17 base64
+// (URL-safe alphabet) characters, as printed by cmd/mylogin-key, and the
+// full form as 40 hexadecimal digits.
+func (k *Key) UnmarshalText(text []byte) error {
+	var key Key
+	switch len(text) {
+	case 2 * len(key):
+		if _, err := hex.Decode(key[:], text); err != nil {
+			return fmt.Errorf("invalid key: %v", err)
+		}
+	case compactKeyHexLen:
+		var compact [compactKeyLen]byte
+		// Complete the last byte
+		if _, err := hex.Decode(compact[:], append(text[:len(text):len(text)], '0')); err != nil {
+			return fmt.Errorf("invalid key: %v", err)
+		}
+		key.uncompact(&compact)
+	case compactKeyBase64n:
+		var compact [compactKeyLen]byte
+		// Complete the last byte
+		if _, err := base64.RawURLEncoding.Decode(compact[:], append(text[:len(text):len(text)], 'A')); err != nil {
+			return fmt.Errorf("invalid key: %v", err)
+		}
+		key.uncompact(&compact)
+	default:
+		return fmt.Errorf("invalid key: length %d, expected %d, %d or %d", len(text), compactKeyHexLen, compactKeyBase64n, 2*len(key))
+	}
+	if key.IsZero() {
+		return errors.New("invalid key: zero")
+	}
+	*k = key
+	return nil
+}
+
+// compact packs the 5 low bits of each byte.
+func (k *Key) compact() (compact [compactKeyLen]byte) {
+	var j int
+	var acc uint // accumulator
+	var accBits uint
+	for _, b := range k {
+		acc = (acc << keyBits) | uint(b&(1<<keyBits-1))
+		accBits += keyBits
+		for accBits >= 8 {
+			accBits -= 8
+			compact[j] = byte(acc >> accBits)
+			j++
+		}
+	}
+	// Only 4 bits remain
+	compact[j] = byte(acc << (8 - accBits))
+	return
+}
+
+// uncompact is the reverse of compact.
+func (k *Key) uncompact(compact *[compactKeyLen]byte) {
+	var j int
+	var acc uint // accumulator
+	var accBits uint
+	for i := range k {
+		for accBits < keyBits {
+			acc = (acc << 8) | uint(compact[j])
+			j++
+			accBits += 8
+		}
+		accBits -= keyBits
+		k[i] = byte(acc>>accBits) & (1<<keyBits - 1)
+	}
+}
@@ -2,6 +2,8 @@ package mylogin_test
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/rand"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -84,3 +86,70 @@ func testFileKeyBits(t *testing.T, mysql_config_editor string, filename string)
 
 	t.Logf("%s: %X (3 high bits always cleared)", filename, key)
 }
+
+func TestKeyText(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = key.Validate(); err != nil {
+			t.Fatalf("%X: %v", key, err)
+		}
+		text, err := key.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(text) != 25 {
+			t.Fatalf("%X: %q: compact form expected", key, text)
+		}
+		var got mylogin.Key
+		if err = got.UnmarshalText(text); err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		if got != key {
+			t.Fatalf("%q: got %X, expected %X", text, got, key)
+		}
+		// Lower case is accepted
+		if err = got.UnmarshalText(bytes.ToLower(text)); err != nil || got != key {
+			t.Fatalf("%q: got %X, expected %X (%v)", bytes.ToLower(text), got, key, err)
+		}
+	}
+
+	// Same key in the 3 forms
+	var hexKey, b64Key, fullKey mylogin.Key
+	for _, test := range []struct {
+		key  *mylogin.Key
+		text string
+	}{
+		{&hexKey, "08864298E84A96C6B9F08CA74"},
+		{&b64Key, "CIZCmOhKlsa58IynQ"},
+		{&fullKey, "0102030405060708090A0B0C0D0E0F1011121314"},
+	} {
+		if err := test.key.UnmarshalText([]byte(test.text)); err != nil {
+			t.Errorf("%q: %v", test.text, err)
+		}
+	}
+	if hexKey != fullKey || b64Key != fullKey {
+		t.Errorf("%X %X %X", hexKey, b64Key, fullKey)
+	}
+
+	bigKey := mylogin.Key{0: 0x20, 19: 1}
+	if bigKey.Validate() == nil {
+		t.Error("Validate: error expected")
+	}
+	text, _ := bigKey.MarshalText()
+	if len(text) != 40 {
+		t.Errorf("%q: full form expected", text)
+	}
+	if (mylogin.Key{}).Validate() == nil {
+		t.Error("Validate: error expected for zero key")
+	}
+
+	for _, text := range []string{"", "XYZ", "08864298E84A96C6B9F08CA7Z", "0000000000000000000000000"} {
+		var key mylogin.Key
+		if err := key.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("%q: error expected", text)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package mylogin
+
+import "os"
+
+// Layer is a login file of Layers.
+type Layer struct {
+	// Name identifies the file in the traces (see Source.File).
+	Name string
+	// Optional layers are skipped if the file doesn't exist.
+	Optional bool
+	read     func() (*Source, error)
+}
+
+// FileLayer returns a Layer for the mylogin.cnf file at path filename.
+// Like ReadSections, the file is rejected if its permissions are unsafe.
+func FileLayer(filename string, optional bool) Layer {
+	return (&ReadOptions{}).FileLayer(filename, optional)
+}
+
+// FileLayer is like the FileLayer function with options.
+func (o *ReadOptions) FileLayer(filename string, optional bool) Layer {
+	return Layer{
+		Name:     filename,
+		Optional: optional,
+		read: func() (*Source, error) {
+			return o.ReadSource(filename)
+		},
+	}
+}
+
+// Layers is an ordered list of login files, such as a shared read-only file
+// followed by the personal file of the user.
+//
+// Sections are merged across the files: for each section name, in the
+// order given to ReadLogin, the sections of each file are merged in order.
+// So a login path has the precedence over [client], whatever the file, and
+// for the same section the last file has the precedence for each option.
+type Layers []Layer
+
+// Read reads the files. The Source of an optional file that doesn't exist
+// is omitted.
+func (layers Layers) Read() ([]*Source, error) {
+	sources := make([]*Source, 0, len(layers))
+	for _, l := range layers {
+		src, err := l.read()
+		if err != nil {
+			if l.Optional && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// ReadSections reads the files and returns the sections of all files, in
+// order. Use Sections.Normalize to obtain the merged sections.
+func (layers Layers) ReadSections() (Sections, error) {
+	sources, err := layers.Read()
+	if err != nil {
+		return nil, err
+	}
+	var sections Sections
+	for _, src := range sources {
+		sections = append(sections, src.Sections...)
+	}
+	return sections, nil
+}
+
+// ReadLogin reads the files, extracts the requested sections and merges
+// them to obtain a single Login (that may be empty). See MergeTrace for the
+// precedence.
+func (layers Layers) ReadLogin(sectionNames []string) (*Login, error) {
+	sources, err := layers.Read()
+	if err != nil {
+		return nil, err
+	}
+	login, _ := MergeTrace(sectionNames, sources...)
+	return login, nil
+}
@@ -0,0 +1,115 @@
+package mylogin_test
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, sections mylogin.Sections) string {
+		t.Helper()
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(dir, name)
+		if err = mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	shared := write("shared.cnf", mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{Host: stringPtr("db")}},
+		{Name: "reporting", Login: mylogin.Login{User: stringPtr("ro"), Password: stringPtr("ro")}},
+	})
+	personal := write("personal.cnf", mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen")}},
+		{Name: "reporting", Login: mylogin.Login{Password: stringPtr("secret")}},
+	})
+	missing := filepath.Join(dir, "missing.cnf")
+
+	layers := mylogin.Layers{
+		mylogin.FileLayer(missing, true),
+		mylogin.FileLayer(shared, false),
+		mylogin.FileLayer(personal, false),
+	}
+	sources, err := layers.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[0].File != shared || sources[1].File != personal {
+		t.Errorf("unexpected sources: %+v", sources)
+	}
+
+	login, err := layers.ReadLogin([]string{"client", "reporting"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The login path has the precedence over [client], even from an earlier
+	// file
+	if dsn := login.DSN(); dsn != "ro:secret@tcp(db:3306)/" {
+		t.Errorf("got %s", dsn)
+	}
+
+	sections, err := layers.ReadSections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen"), Host: stringPtr("db")}},
+		{Name: "reporting", Login: mylogin.Login{User: stringPtr("ro"), Password: stringPtr("secret")}},
+	}
+	if got := sections.Normalize(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v, expected %#v", got, expected)
+	}
+
+	if _, err = (mylogin.Layers{mylogin.FileLayer(missing, false)}).Read(); !os.IsNotExist(err) {
+		t.Errorf("required layer: got %v", err)
+	}
+}
+
+// TestLayersSectionOrder checks that the order of the section names has the
+// precedence over the order of the sections in the file, like with
+// Sections.Merge.
+func TestLayersSectionOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("c")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("p")}},
+	}
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "login.cnf")
+	if err = mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, names := range [][]string{{"client", "prod"}, {"prod", "client"}} {
+		login, err := mylogin.ReadLogin(filename, names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := sections.Merge(names); !reflect.DeepEqual(login, expected) {
+			t.Errorf("%q: got %v, expected %v", names, login, expected)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package mylogin
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -30,6 +31,9 @@ func (l *Login) IsEmpty() bool {
 //
 // The DSN returned always has a '/' at the end.
 // The DSN for an empty Login is just "/".
+//
+// Unlike the MySQL client, a missing host or "localhost" is not resolved
+// to the default Unix socket: use ResolveTransport first for that.
 func (l *Login) DSN() string {
 	// Handles the case where login is nil
 	if l.IsEmpty() {
@@ -89,11 +93,59 @@ var unquote = strings.NewReplacer(
 	`\\`, `\`,
 ).Replace
 
+// escape and quote are the reverse of unescape and unquote.
+var escape = strings.NewReplacer(
+	`\`, `\\`,
+	"\b", `\b`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+).Replace
+
+var quote = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+).Replace
+
+type loginOption struct {
+	name  string
+	value *string
+}
+
+// options returns the options of l, set or not, in the same order as
+// mysql_config_editor.
+func (l *Login) options() []loginOption {
+	return []loginOption{
+		{"user", l.User},
+		{"password", l.Password},
+		{"host", l.Host},
+		{"socket", l.Socket},
+		{"port", l.Port},
+	}
+}
+
+// writeTo writes the options of l in the format of mysql_config_editor
+// (strings are quoted since 8.0.24).
+func (l *Login) writeTo(b *bytes.Buffer) {
+	for _, opt := range l.options() {
+		if opt.value == nil {
+			continue
+		}
+		b.WriteString(opt.name)
+		b.WriteString(` = "`)
+		b.WriteString(quote(escape(*opt.value)))
+		b.WriteString("\"\n")
+	}
+}
+
 func (l *Login) parseLine(line string) error {
 	// Reference code:
 	// https://github.com/mysql/mysql-shell/blob/master/mysql-secret-store/login-path/login_path_helper.cc#L52
 
 	s := strings.SplitN(line, " = ", 2)
+	if len(s) != 2 {
+		return errors.New("invalid option line")
+	}
 
 	v := s[1]
 
@@ -20,7 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // DefaultSection is the name of the base section used by all MySQL client tools.
@@ -53,21 +56,39 @@ func (k *Key) cipher() cipher.Block {
 // See [math/rand.Read] and [crypto/rand.Read] as possible sources.
 //
 // The generated key has the 3 high bits cleared so each byte is non-printable.
+//
+// An error is returned if readRandom fails, or if it doesn't return enough
+// bytes to fill the key.
 func NewKey(readRandom func([]byte) (int, error)) (Key, error) {
 	var key Key
 	// FIXME We will finally use only 5 bits of each byte.
 	//       We should take much less bytes and spread them.
-	_, err := readRandom(key[:])
+	n, err := readRandom(key[:])
 	if err != nil {
-		return Key{}, nil
+		return Key{}, err
+	}
+	if n < len(key) {
+		return Key{}, io.ErrUnexpectedEOF
 	}
 	for i := range key {
 		// Clear the high bits
 		key[i] = key[i] & 0x1F
 	}
+	if key.IsZero() {
+		return Key{}, errors.New("weak key: the random source returned only zeros")
+	}
 	return key, nil
 }
 
+// Rekey re-encrypts a mylogin.cnf file with a new key (see NewKey) created
+// from rand, such as [crypto/rand.Reader]. The byte order and the content
+// of the file are preserved. The file is replaced atomically (see
+// WriteFile). Like ReadFile, the file is rejected if its permissions are
+// unsafe.
+func Rekey(filename string, rand io.Reader) error {
+	return (&ReadOptions{}).Rekey(filename, rand)
+}
+
 // DefaultFile returns the path to the default mylogin.cnf file:
 //
 //	Windows: %APPDATA%/MySQL/.mylogin.cnf
@@ -85,40 +106,60 @@ func DefaultFile() string {
 }
 
 // ReadLogin reads a mylogin.cnf file, extracts the requested sections and
-// merges them to obtain a single Login (that may be empty).
+// merges them to obtain a single Login (that may be empty). To read several
+// files, see Layers.
+//
+// Like the MySQL client, the file is rejected if its permissions are unsafe
+// (see CheckPermissions). Use ReadOptions to change that policy.
 func ReadLogin(filename string, sectionNames []string) (login *Login, err error) {
-	sections, err := ReadSections(filename)
-	if err != nil {
-		return
-	}
-	login = sections.Merge(sectionNames)
-	return
+	return (&ReadOptions{}).ReadLogin(filename, sectionNames)
 }
 
 // ReadSections reads all Sections of a mylogin.cnf file.
+//
+// Like the MySQL client, the file is rejected if its permissions are unsafe
+// (see CheckPermissions). Use ReadOptions to change that policy.
 func ReadSections(filename string) (sections Sections, err error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return
-	}
-	defer f.Close()
+	return (&ReadOptions{}).ReadSections(filename)
+}
 
-	file, err := Decode(bufio.NewReader(f))
+// ReadSectionsFrom decodes (see Decode) and parses (see Parse) the content
+// of a mylogin.cnf file.
+func ReadSectionsFrom(r io.Reader) (Sections, error) {
+	file, err := Decode(r)
 	if err != nil {
-		return
+		return nil, err
 	}
 	return Parse(file.PlainText())
 }
 
+// ReadFile reads and decrypts a mylogin.cnf file.
+// The PlainText of the returned File can be read multiple times.
+//
+// Like the MySQL client, the file is rejected if its permissions are unsafe
+// (see CheckPermissions). Use ReadOptions to change that policy.
+func ReadFile(filename string) (File, error) {
+	return (&ReadOptions{}).ReadFile(filename)
+}
+
 // Parse parses the plaintext content of a mylogin.cnf file
 // and returns the structured content.
 func Parse(rd io.Reader) (sections Sections, err error) {
+	return parse(rd, nil)
+}
+
+// parse is Parse. If not nil, position is called with the line number
+// (starting at 1) of each option, with the index of its section.
+func parse(rd io.Reader, position func(section int, option string, line int)) (sections Sections, err error) {
 	// Reference code: https://github.com/mysql/mysql-shell/blob/master/mysql-secret-store/login-path/login_path_helper.cc#L52
 	var login *Login
 	scanner := bufio.NewScanner(rd)
-	for scanner.Scan() {
+	for n := 1; scanner.Scan(); n++ {
 		line := scanner.Text()
-		if line[0] == '[' {
+		if len(line) > 0 && line[0] == '[' {
+			if len(line) < 2 || line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: invalid section header %q", n, line)
+			}
 			sections = append(sections,
 				Section{Name: line[1 : len(line)-1]})
 			login = &sections[len(sections)-1].Login
@@ -126,6 +167,9 @@ func Parse(rd io.Reader) (sections Sections, err error) {
 			if err = login.parseLine(line); err != nil {
 				return nil, err
 			}
+			if position != nil {
+				position(len(sections)-1, line[:strings.Index(line, " = ")], n)
+			}
 		}
 	}
 	return
@@ -142,10 +186,12 @@ type File interface {
 }
 
 type decoder struct {
+	header    [4]byte
 	key       Key
 	byteOrder binary.ByteOrder
 
 	input  io.Reader
+	offset int64 // Offset in the file of the next chunk
 	chunk  [256 * aes.BlockSize]byte
 	buffer []byte // Slice pointing to chunk
 }
@@ -174,9 +220,9 @@ func Decode(input io.Reader) (File, error) {
 
 	in := bufio.NewReader(input)
 
+	var header [4]byte
 	// Skip first 4 bytes
-	head4 := make([]byte, 4)
-	n, err := io.ReadFull(in, head4)
+	n, err := io.ReadFull(in, header[:])
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +253,13 @@ func Decode(input io.Reader) (File, error) {
 		byteOrder = binary.LittleEndian
 	}
 
-	return &decoder{key: key, input: in, byteOrder: byteOrder}, nil
+	return &decoder{
+		header:    header,
+		key:       key,
+		input:     in,
+		offset:    int64(len(header) + len(key)),
+		byteOrder: byteOrder,
+	}, nil
 }
 
 // Read is the PlainText reader.
@@ -220,59 +272,76 @@ func (d *decoder) Read(buf []byte) (n int, err error) {
 		d.buffer = d.buffer[n:]
 		return
 	}
-	var size int32
+	var size int
 	for {
-		// Read a new chunk
-		if err = binary.Read(d.input, d.byteOrder, &size); err != nil {
+		if size, err = d.readChunk(); err != nil {
 			return 0, err
 		}
 		if size != 0 {
 			break
 		}
 	}
+
+	d.buffer, _ = unpad(d.chunk[:size])
+
+	n = copy(buf, d.buffer)
+	d.buffer = d.buffer[n:]
+	return
+}
+
+// readChunk reads the next chunk and decrypts it into d.chunk.
+// io.EOF is returned only at the end of the last chunk.
+func (d *decoder) readChunk() (int, error) {
+	var size int32
+	if err := binary.Read(d.input, d.byteOrder, &size); err != nil {
+		return 0, err
+	}
+	d.offset += 4
 	if size < 0 || int(size) > len(d.chunk) || size%aes.BlockSize != 0 {
 		return 0, fmt.Errorf("invalid block size: %d", size)
 	}
-	n, err = io.ReadFull(d.input, d.chunk[:size])
+	n, err := io.ReadFull(d.input, d.chunk[:size])
+	d.offset += int64(n)
 	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
 		return 0, err
 	}
-	if n != int(size) {
-		return 0, fmt.Errorf("invalid read size: got %d, expected %d", n, size)
-	}
 
 	blockCipher := d.key.cipher()
 
 	// Each 16-bytes block is encoded with a null IV
-	d.buffer = d.chunk[:size]
 	for i := 0; i < int(size); i += aes.BlockSize {
 		cbc := cipher.NewCBCDecrypter(blockCipher, make([]byte, aes.BlockSize))
 		b := d.chunk[i : i+aes.BlockSize]
 		cbc.CryptBlocks(b, b)
 	}
+	return int(size), nil
+}
 
-	// Remove PKCS#7 padding
+// unpad removes the PKCS#7 padding of a decrypted chunk. The number of
+// padding bytes is 0 if the padding is invalid.
+func unpad(chunk []byte) ([]byte, int) {
+	if len(chunk) == 0 {
+		return chunk, 0
+	}
 	// last byte value gives the number of padding byte
 	// each padding byte has that value
-	padding := d.buffer[len(d.buffer)-1]
+	padding := chunk[len(chunk)-1]
 	// Note that mysql_config_editor generates up to 16 bytes of padding
 	// which is a full AES block, so 16 encrypted bytes just to be drop when
 	// reading.
 	// Is it a bug or some nasty redundancy to reveal the encryption key?
-	if padding > 0 && padding <= aes.BlockSize {
-		//log.Printf("Padding: %d\n", padding)
-		for _, c := range d.buffer[len(d.buffer)-int(padding):] {
-			if c != padding {
-				padding = 0
-				break
-			}
+	if padding == 0 || padding > aes.BlockSize {
+		return chunk, 0
+	}
+	for _, c := range chunk[len(chunk)-int(padding):] {
+		if c != padding {
+			return chunk, 0
 		}
-		d.buffer = d.buffer[:len(d.buffer)-int(padding)]
 	}
-
-	n = copy(buf, d.buffer)
-	d.buffer = d.buffer[n:]
-	return
+	return chunk[:len(chunk)-int(padding)], int(padding)
 }
 
 // Encode writes a mylogin.cnf content encrypted
@@ -332,6 +401,52 @@ func Encode(w io.Writer, f File) (err error) {
 	return
 }
 
+// WriteFile writes f encrypted (see Encode) to filename, with mode 0600 like
+// mysql_config_editor. An existing file is atomically replaced. If filename
+// is a symbolic link, the target is replaced and the link is kept.
+func WriteFile(filename string, f File) (err error) {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// ioutil.TempFile creates the file with mode 0600
+	if err = Encode(tmp, f); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// NewFile returns a File with the given sections as content, to be encrypted
+// with key. If byteOrder is nil, [encoding/binary.LittleEndian] is used.
+//
+// See Encode.
+func NewFile(key Key, byteOrder binary.ByteOrder, sections Sections) File {
+	if byteOrder == nil {
+		byteOrder = binary.LittleEndian
+	}
+	var b bytes.Buffer
+	sections.WriteTo(&b)
+	return &file{K: key, B: byteOrder, PT: b.Bytes()}
+}
+
 // TODO find a good name
 type file struct {
 	K  Key
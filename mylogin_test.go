@@ -2,6 +2,7 @@ package mylogin
 
 import (
 	"crypto/rand"
+	"errors"
 	"testing"
 )
 
@@ -36,3 +37,33 @@ func TestKeyNew(t *testing.T) {
 		}
 	}
 }
+
+func TestKeyNewError(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		read func([]byte) (int, error)
+	}{
+		{"error", func([]byte) (int, error) { return 0, errors.New("no entropy") }},
+		{"short", func(b []byte) (int, error) {
+			for i := range b[:10] {
+				b[i] = 1
+			}
+			return 10, nil
+		}},
+		{"zero", func(b []byte) (int, error) { return len(b), nil }},
+		{"high bits", func(b []byte) (int, error) {
+			for i := range b {
+				b[i] = 0xE0
+			}
+			return len(b), nil
+		}},
+	} {
+		key, err := NewKey(test.read)
+		if err == nil {
+			t.Errorf("%s: error expected", test.name)
+		}
+		if !key.IsZero() {
+			t.Errorf("%s: zero key expected on error", test.name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package mysqltls registers the TLS options of MySQL option files (see
+// [github.com/dolmen-go/mylogin.TLSOptions]) with the driver
+// [github.com/go-sql-driver/mysql].
+//
+// This is a separate package so that the mylogin package doesn't depend on
+// the driver.
+//
+// Example:
+//
+//	opts, err := mylogin.ReadTLSOptions(os.ExpandEnv("${HOME}/.my.cnf"), []string{"client"})
+//	...
+//	cfg, err := mysqltls.Config(login, "mylogin", opts)
+//	...
+//	db, err := sql.Open("mysql", cfg.FormatDSN())
+package mysqltls
+
+import (
+	"net/url"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/go-sql-driver/mysql"
+)
+
+// Register registers the TLS configuration built from opts (see
+// mylogin.TLSOptions.Config) with [mysql.RegisterTLSConfig] under name, and
+// returns the value for the tls parameter of a DSN: "false" if TLS is
+// disabled, else name.
+//
+// The driver can't fall back to an unencrypted connection if the server
+// doesn't support TLS, like the MySQL client does with ssl-mode=PREFERRED.
+// So PREFERRED, which is also the mode if opts is nil or sets no option,
+// gives "false": set ssl-mode=REQUIRED (or a VERIFY mode) to encrypt.
+func Register(name string, opts *mylogin.TLSOptions) (string, error) {
+	cfg, err := opts.Config()
+	if err != nil {
+		return "", err
+	}
+	if cfg == nil || opts.EffectiveMode() == mylogin.SSLModePreferred {
+		mysql.DeregisterTLSConfig(name)
+		return "false", nil
+	}
+	if err = mysql.RegisterTLSConfig(name, cfg); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// DSN returns the DSN of login (see mylogin.Login.DSN) followed by database
+// and with the TLS configuration of opts registered under name (see
+// Register).
+func DSN(login *mylogin.Login, database string, name string, opts *mylogin.TLSOptions) (string, error) {
+	tls, err := Register(name, opts)
+	if err != nil {
+		return "", err
+	}
+	return login.DSN() + database + "?tls=" + url.QueryEscape(tls), nil
+}
+
+// Config returns the driver configuration of login with the TLS
+// configuration of opts registered under name (see Register).
+func Config(login *mylogin.Login, name string, opts *mylogin.TLSOptions) (*mysql.Config, error) {
+	dsn, err := DSN(login, "", name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.ParseDSN(dsn)
+}
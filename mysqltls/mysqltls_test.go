@@ -0,0 +1,47 @@
+package mysqltls_test
+
+import (
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/mysqltls"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestConfig(t *testing.T) {
+	login := &mylogin.Login{User: stringPtr("me"), Host: stringPtr("db.example")}
+
+	cfg, err := mysqltls.Config(login, "mylogin-test", &mylogin.TLSOptions{Mode: mylogin.SSLModeRequired})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TLSConfig != "mylogin-test" || cfg.User != "me" || cfg.Addr != "db.example:3306" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	dsn, err := mysqltls.DSN(login, "test", "mylogin-test", &mylogin.TLSOptions{Mode: mylogin.SSLModeDisabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn != "me@tcp(db.example:3306)/test?tls=false" {
+		t.Errorf("got %s", dsn)
+	}
+
+	// PREFERRED (the default) can't fall back to plain text with the
+	// driver: no TLS
+	for _, opts := range []*mylogin.TLSOptions{nil, {}, {Mode: mylogin.SSLModePreferred}} {
+		if tls, err := mysqltls.Register("mylogin-test", opts); err != nil || tls != "false" {
+			t.Errorf("%+v: got %q, %v", opts, tls, err)
+		}
+	}
+	if tls, err := mysqltls.Register("mylogin-test", &mylogin.TLSOptions{CA: "testdata/missing.pem"}); err == nil {
+		t.Errorf("VERIFY_CA with a missing CA: got %q", tls)
+	}
+
+	if _, err = mysqltls.Register("mylogin-test", &mylogin.TLSOptions{Mode: "bad"}); err == nil {
+		t.Error("error expected")
+	}
+}
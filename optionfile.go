@@ -0,0 +1,117 @@
+package mylogin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadOptionFile reads the options of the given groups from a plain text
+// MySQL option file such as ~/.my.cnf or /etc/mysql/my.cnf.
+//
+// Like the MySQL client, options are read in the order of the file (not of
+// groups), so the last value has the precedence. Option names are
+// normalized with '-' instead of '_'. The !include and !includedir
+// directives are followed.
+//
+// Reference: https://dev.mysql.com/doc/refman/8.0/en/option-files.html
+func ReadOptionFile(filename string, groups []string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		wanted[g] = true
+	}
+	options := make(map[string]string)
+	if err := readOptionFile(filename, wanted, options, 0); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+func readOptionFile(filename string, groups map[string]bool, options map[string]string, depth int) error {
+	if depth > 10 {
+		return fmt.Errorf("%s: too many levels of !include", filename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var inGroup bool
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case strings.HasPrefix(line, "!include "):
+			err = readOptionFile(strings.TrimSpace(line[len("!include "):]), groups, options, depth+1)
+		case strings.HasPrefix(line, "!includedir "):
+			var files []string
+			files, err = filepath.Glob(filepath.Join(strings.TrimSpace(line[len("!includedir "):]), "*.cnf"))
+			sort.Strings(files)
+			for _, file := range files {
+				if err == nil {
+					err = readOptionFile(file, groups, options, depth+1)
+				}
+			}
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				err = errors.New("invalid group")
+			} else {
+				inGroup = groups[strings.TrimSpace(line[1:len(line)-1])]
+			}
+		case inGroup:
+			var name, value string
+			if name, value, err = parseOptionLine(line); err == nil {
+				options[name] = value
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", filename, lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+var optionEscapes = strings.NewReplacer(
+	`\b`, "\b",
+	`\t`, "\t",
+	`\n`, "\n",
+	`\r`, "\r",
+	`\\`, `\`,
+	`\s`, " ",
+)
+
+// parseOptionLine parses a line "name", "name=value" or "name = value".
+func parseOptionLine(line string) (name, value string, err error) {
+	i := strings.IndexByte(line, '=')
+	if i < 0 {
+		name = line
+	} else {
+		name, value = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	if name == "" {
+		return "", "", errors.New("invalid option line")
+	}
+	name = strings.Replace(name, "_", "-", -1)
+
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		end := strings.IndexByte(value[1:], value[0])
+		if end < 0 {
+			return "", "", errors.New("unterminated quoted value")
+		}
+		// Anything after the closing quote must be a comment
+		if rest := strings.TrimSpace(value[end+2:]); rest != "" && rest[0] != '#' {
+			return "", "", errors.New("garbage after quoted value")
+		}
+		value = value[1 : end+1]
+	} else if i := strings.IndexByte(value, '#'); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return name, optionEscapes.Replace(value), nil
+}
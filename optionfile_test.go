@@ -0,0 +1,89 @@
+package mylogin_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestReadOptionFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confd := filepath.Join(dir, "conf.d")
+	if err = os.Mkdir(confd, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"my.cnf": `# Comment
+[mysqld]
+ssl-ca = /server/ca.pem
+
+[client]
+ssl_ca = /etc/mysql/ca.pem
+ssl-mode=VERIFY_IDENTITY  # trailing comment
+password = "a#b\tc"
+user = 'me'
+skip-ssl-verify
+!includedir ` + confd + `
+
+[mysql]
+ssl-cert = "/home/me/cert.pem"
+`,
+		"conf.d/tls.cnf": `[client]
+ssl-key = /home/me/key.pem
+ssl-mode = VERIFY_CA
+`,
+		"conf.d/ignored.txt": `[client]
+user = ignored
+`,
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filename := filepath.Join(dir, "my.cnf")
+	options, err := mylogin.ReadOptionFile(filename, []string{"client", "mysql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"ssl-ca":          "/etc/mysql/ca.pem",
+		"ssl-mode":        "VERIFY_CA",
+		"password":        "a#b\tc",
+		"user":            "me",
+		"skip-ssl-verify": "",
+		"ssl-key":         "/home/me/key.pem",
+		"ssl-cert":        "/home/me/cert.pem",
+	}
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("got %q, expected %q", options, expected)
+	}
+
+	tlsOpts, err := mylogin.ReadTLSOptions(filename, []string{"client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTLS := mylogin.TLSOptions{Mode: "VERIFY_CA", CA: "/etc/mysql/ca.pem", Key: "/home/me/key.pem"}
+	if tlsOpts == nil || *tlsOpts != expectedTLS {
+		t.Errorf("got %+v, expected %+v", tlsOpts, expectedTLS)
+	}
+	if tlsOpts, err = mylogin.ReadTLSOptions(filename, []string{"mysqldump"}); err != nil || tlsOpts != nil {
+		t.Errorf("mysqldump: got %+v, %v", tlsOpts, err)
+	}
+
+	if err = ioutil.WriteFile(filename, []byte("[client]\nuser = 'me\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = mylogin.ReadOptionFile(filename, []string{"client"}); err == nil {
+		t.Error("error expected")
+	}
+}
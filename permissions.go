@@ -0,0 +1,170 @@
+package mylogin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// PermissionPolicy is the action taken by the reading functions when a file
+// has unsafe permissions. See CheckPermissions.
+type PermissionPolicy int
+
+const (
+	// PermissionRefuse rejects the file with a *PermissionError, like the
+	// MySQL client which ignores such files. This is the default.
+	PermissionRefuse PermissionPolicy = iota
+	// PermissionWarn reports the problems to ReadOptions.Warn and reads the
+	// file anyway.
+	PermissionWarn
+	// PermissionIgnore skips the checks.
+	PermissionIgnore
+)
+
+var permissionPolicyNames = [...]string{
+	PermissionRefuse: "refuse",
+	PermissionWarn:   "warn",
+	PermissionIgnore: "ignore",
+}
+
+func (p PermissionPolicy) String() string {
+	if p < 0 || int(p) >= len(permissionPolicyNames) {
+		return "PermissionPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+	return permissionPolicyNames[p]
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (p PermissionPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (p *PermissionPolicy) UnmarshalText(text []byte) error {
+	for i, name := range permissionPolicyNames {
+		if string(text) == name {
+			*p = PermissionPolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown permission policy %q", text)
+}
+
+// PermissionError is a problem reported by CheckPermissions.
+type PermissionError struct {
+	Path    string
+	Problem string
+}
+
+func (e *PermissionError) Error() string {
+	return e.Path + ": " + e.Problem
+}
+
+// CheckPermissions checks that a mylogin.cnf file is safe to use. The MySQL
+// client ignores a login file that is executable or accessible by the group
+// or others. The checks also reject a file not owned by the current user
+// and a symbolic link that points outside of the home directory.
+//
+// On Windows no checks are done, like the MySQL client.
+//
+// The returned errors are of type *PermissionError, except the errors
+// reported by [os.Open].
+func CheckPermissions(filename string) []error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return []error{err}
+	}
+	defer f.Close()
+	// see checkfile.go, checkfile_windows.go
+	return platformCheckFile(filename, f, false)
+}
+
+// ReadOptions are the options of the reading functions.
+// The zero value applies the default policies.
+type ReadOptions struct {
+	Permissions PermissionPolicy
+	// Warn is called for each problem found with PermissionWarn.
+	// If nil, the problems are ignored.
+	Warn func(err error)
+	// Shared relaxes the checks for a file shared with other users (see
+	// Layers): it may be owned by another user, be readable by the group
+	// and others, and be the target of a symbolic link outside of the home
+	// directory. It must still be writable only by its owner.
+	Shared bool
+}
+
+// Open opens filename and applies the permission policy to the opened
+// file. Use it to read a login file with another function than the
+// methods of ReadOptions, such as Decode, Inspect or Recover.
+func (o *ReadOptions) Open(filename string) (*os.File, error) {
+	f, err := os.Open(filename)
+	if err != nil || o.Permissions == PermissionIgnore {
+		return f, err
+	}
+	for _, err := range platformCheckFile(filename, f, o.Shared) {
+		if _, ok := err.(*PermissionError); !ok || o.Permissions == PermissionRefuse {
+			f.Close()
+			return nil, err
+		}
+		if o.Warn != nil {
+			o.Warn(err)
+		}
+	}
+	return f, nil
+}
+
+// ReadLogin is like the ReadLogin function with options.
+func (o *ReadOptions) ReadLogin(filename string, sectionNames []string) (*Login, error) {
+	return Layers{o.FileLayer(filename, false)}.ReadLogin(sectionNames)
+}
+
+// ReadSections is like the ReadSections function with options.
+func (o *ReadOptions) ReadSections(filename string) (Sections, error) {
+	f, err := o.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSectionsFrom(f)
+}
+
+// ReadFile is like the ReadFile function with options.
+func (o *ReadOptions) ReadFile(filename string) (File, error) {
+	f, err := o.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := Decode(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	pt, err := ioutil.ReadAll(d.PlainText())
+	if err != nil {
+		return nil, err
+	}
+	return &file{K: d.Key(), B: d.ByteOrder(), PT: pt}, nil
+}
+
+// Rekey is like the Rekey function with options.
+func (o *ReadOptions) Rekey(filename string, rand io.Reader) error {
+	f, err := o.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	key, err := NewKey(func(b []byte) (int, error) {
+		return io.ReadFull(rand, b)
+	})
+	if err != nil {
+		return err
+	}
+	plainText, err := ioutil.ReadAll(f.PlainText())
+	if err != nil {
+		return err
+	}
+	return WriteFile(filename, &file{K: key, B: f.ByteOrder(), PT: plainText})
+}
@@ -0,0 +1,84 @@
+// Package prompt reads passwords on the terminal, with echo disabled.
+//
+// It is separate from package mylogin to keep the dependency on
+// [golang.org/x/term] out of the programs that only read login files. Use
+// Password as [github.com/dolmen-go/mylogin.Flags.ReadPassword].
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Password prints prompt on stderr and reads a line from stdin. If stdin
+// is a terminal, echo is disabled while reading and restored even if the
+// program is interrupted. Otherwise stdin is not read past the line, so
+// that the rest of the input is left to the caller.
+func Password(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(os.Stdin)
+	}
+
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", err
+	}
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(sig)
+		close(done)
+	}()
+	go func() {
+		select {
+		case s := <-sig:
+			// Restore echo, then let the signal do what it would have done
+			term.Restore(fd, state)
+			fmt.Fprintln(os.Stderr)
+			signal.Stop(sig)
+			if p, err := os.FindProcess(os.Getpid()); err != nil || p.Signal(s) != nil {
+				os.Exit(1)
+			}
+		case <-done:
+		}
+	}()
+
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// readLine reads r up to the end of line without buffering, so nothing
+// after the line is consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	var b [1]byte
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
@@ -0,0 +1,39 @@
+package prompt_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dolmen-go/mylogin/prompt"
+)
+
+func TestPasswordPipe(t *testing.T) {
+	f, err := ioutil.TempFile("", "prompt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err = f.WriteString("secret\r\nrest\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(0, 0)
+
+	defer func(stdin, stderr *os.File) {
+		os.Stdin, os.Stderr = stdin, stderr
+	}(os.Stdin, os.Stderr)
+	os.Stdin = f
+	os.Stderr, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer os.Stderr.Close()
+
+	password, err := prompt.Password("Password: ")
+	if err != nil || password != "secret" {
+		t.Errorf("got %q, %v", password, err)
+	}
+	// The input after the password is left unread
+	rest, _ := ioutil.ReadAll(f)
+	if string(rest) != "rest\n" {
+		t.Errorf("rest: got %q", rest)
+	}
+}
@@ -0,0 +1,150 @@
+package mylogin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Recovery is the result of Recover.
+type Recovery struct {
+	// File has the same key and byte order as the original file, and
+	// the recovered lines as plaintext. It can be written with WriteFile.
+	File File
+	// Sections are the recovered sections.
+	Sections Sections
+	// Lost lists the parts of the file that could not be decoded.
+	Lost []LostRange
+	// Orphans lists the options that were decoded but dropped because they
+	// follow lost data that might have been the header of their section.
+	Orphans []Orphan
+}
+
+// Orphan is an option dropped by Recover. The value is not included.
+type Orphan struct {
+	Offset int64
+	Option string
+}
+
+// LostRange is a range of bytes of a file that could not be decoded.
+type LostRange struct {
+	Offset int64
+	Length int64
+}
+
+func (r LostRange) String() string {
+	return fmt.Sprintf("%d bytes at offset %d", r.Length, r.Offset)
+}
+
+// Recover decodes as much as possible of a corrupted mylogin.cnf file.
+//
+// As each line is encrypted in its own chunk, Recover skips the chunks that
+// can't be decoded and re-synchronizes on the next valid chunk by scanning
+// the following bytes. A chunk is valid if it decrypts to a single line
+// that is a section header or a known option, with valid padding.
+// Options that follow lost data are dropped (see Recovery.Orphans) until
+// the next section header, so that they are never attached to the wrong
+// section.
+//
+// An error is returned only if the header and the key can't be read.
+func Recover(r io.Reader) (*Recovery, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	d := f.(*decoder)
+
+	blockCipher := d.key.cipher()
+	var (
+		plainText bytes.Buffer
+		rec       Recovery
+		inSection bool
+		lostFrom  = int64(-1)
+		chunk     []byte
+	)
+	for pos := d.offset; pos < int64(len(content)); {
+		line, size := recoverChunk(content[pos:], d.byteOrder, blockCipher, &chunk)
+		if line == "" {
+			if lostFrom < 0 {
+				lostFrom = pos
+			}
+			pos++
+			continue
+		}
+		if lostFrom >= 0 {
+			rec.Lost = append(rec.Lost, LostRange{Offset: lostFrom, Length: pos - lostFrom})
+			lostFrom = -1
+			// The lost data might include a section header: the
+			// following options can't be attached to the current section
+			inSection = false
+		}
+
+		if line[0] == '[' {
+			inSection = true
+		} else if !inSection {
+			rec.Orphans = append(rec.Orphans, Orphan{Offset: pos, Option: line[:strings.Index(line, " = ")]})
+			pos += int64(size)
+			continue
+		}
+		pos += int64(size)
+		plainText.WriteString(line)
+		plainText.WriteByte('\n')
+	}
+	if lostFrom >= 0 {
+		rec.Lost = append(rec.Lost, LostRange{Offset: lostFrom, Length: int64(len(content)) - lostFrom})
+	}
+
+	rec.File = &file{K: d.key, B: d.byteOrder, PT: plainText.Bytes()}
+	if rec.Sections, err = Parse(rec.File.PlainText()); err != nil {
+		// Not expected as each line has been checked
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// recoverChunk tries to decode a chunk at the start of b. It returns the
+// line (without line break) and the size of the chunk, or an empty line if
+// the chunk is invalid. buf is a reusable buffer.
+func recoverChunk(b []byte, byteOrder binary.ByteOrder, blockCipher cipher.Block, buf *[]byte) (string, int) {
+	if len(b) < 4+aes.BlockSize {
+		return "", 0
+	}
+	size := int(int32(byteOrder.Uint32(b)))
+	if size <= 0 || size > 256*aes.BlockSize || size%aes.BlockSize != 0 || 4+size > len(b) {
+		return "", 0
+	}
+	*buf = append((*buf)[:0], b[4:4+size]...)
+	chunk := *buf
+	for i := 0; i < size; i += aes.BlockSize {
+		cbc := cipher.NewCBCDecrypter(blockCipher, make([]byte, aes.BlockSize))
+		cbc.CryptBlocks(chunk[i:i+aes.BlockSize], chunk[i:i+aes.BlockSize])
+	}
+	plain, padding := unpad(chunk)
+	if padding == 0 || len(plain) < 2 || plain[len(plain)-1] != '\n' {
+		return "", 0
+	}
+	line := string(plain[:len(plain)-1])
+	if strings.ContainsAny(line, "\n\x00") {
+		return "", 0
+	}
+	if line[0] == '[' {
+		if line[len(line)-1] != ']' {
+			return "", 0
+		}
+	} else {
+		var l Login
+		if l.parseLine(line) != nil {
+			return "", 0
+		}
+	}
+	return line, 4 + size
+}
@@ -0,0 +1,90 @@
+package mylogin_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"reflect"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestRecover(t *testing.T) {
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen"), Password: stringPtr("secret")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("admin"), Host: stringPtr("db.example.com")}},
+		{Name: "test", Login: mylogin.Login{User: stringPtr("test"), Port: stringPtr("3307")}},
+	}
+	var b bytes.Buffer
+	if err = mylogin.Encode(&b, mylogin.NewFile(key, nil, sections)); err != nil {
+		t.Fatal(err)
+	}
+	content := b.Bytes()
+
+	insp, err := mylogin.Inspect(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := insp.Chunks // [client] user password [prod] user host [test] user port
+
+	// Intact file
+	rec, err := mylogin.Recover(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rec.Sections, sections) || len(rec.Lost) != 0 || len(rec.Orphans) != 0 {
+		t.Errorf("intact: %+v", rec)
+	}
+
+	corrupted := append([]byte(nil), content...)
+	// Corrupt the data of the password of [client]
+	corrupted[chunks[2].Offset+4+3] ^= 0xFF
+	// Corrupt the size of the [prod] header: its options are orphans.
+	// This makes a single lost range with the password
+	corrupted[chunks[3].Offset] = 0xFF
+	// Truncate the last chunk (port of [test])
+	corrupted = corrupted[:chunks[8].Offset+10]
+
+	rec, err = mylogin.Recover(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen")}},
+		{Name: "test", Login: mylogin.Login{User: stringPtr("test")}},
+	}
+	if !reflect.DeepEqual(rec.Sections, expected) {
+		t.Errorf("got %#v, expected %#v", rec.Sections, expected)
+	}
+	expectedOrphans := []mylogin.Orphan{
+		{Offset: chunks[4].Offset, Option: "user"},
+		{Offset: chunks[5].Offset, Option: "host"},
+	}
+	if !reflect.DeepEqual(rec.Orphans, expectedOrphans) {
+		t.Errorf("orphans: got %+v, expected %+v", rec.Orphans, expectedOrphans)
+	}
+	expectedLost := []mylogin.LostRange{
+		{Offset: chunks[2].Offset, Length: chunks[4].Offset - chunks[2].Offset},
+		{Offset: chunks[8].Offset, Length: 10},
+	}
+	if !reflect.DeepEqual(rec.Lost, expectedLost) {
+		t.Errorf("lost: got %v, expected %v", rec.Lost, expectedLost)
+	}
+
+	// The repaired file is clean
+	b.Reset()
+	if err = mylogin.Encode(&b, rec.File); err != nil {
+		t.Fatal(err)
+	}
+	insp, err = mylogin.Inspect(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !insp.IsClean() || insp.Key != key {
+		t.Errorf("repaired: %+v", insp)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package secretstore
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile creates filename if needed and locks it, waiting for the lock if
+// another process holds it. The lock is released by unlock.
+func lockFile(filename string) (unlock func() error, err error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Closing the file releases the lock
+	return f.Close, nil
+}
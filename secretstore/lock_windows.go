@@ -0,0 +1,22 @@
+package secretstore
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile creates filename if needed and locks it, waiting for the lock if
+// another process holds it. The lock is released by unlock.
+func lockFile(filename string) (unlock func() error, err error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, new(windows.Overlapped)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Closing the file releases the lock
+	return f.Close, nil
+}
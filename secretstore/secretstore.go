@@ -0,0 +1,389 @@
+// Package secretstore implements a MySQL Shell secret store helper backed
+// by a mylogin.cnf file, compatible with mysql-secret-store-login-path.
+//
+// MySQL Shell runs the helper mysql-secret-store-<name> with a command as
+// argument and exchanges JSON documents on its stdin and stdout:
+//
+//	store    {"ServerURL": "user@host:3306", "SecretType": "password", "Secret": "..."}
+//	get      {"ServerURL": "user@host:3306", "SecretType": "password"}  -> Secret
+//	erase    {"ServerURL": "user@host:3306", "SecretType": "password"}
+//	list     -> [{"ServerURL": "user@host:3306", "SecretType": "password"}, ...]
+//	version  -> version string
+//
+// See https://dev.mysql.com/doc/mysql-shell/8.0/en/mysql-shell-pluggable-password-store.html
+//
+// Like the C++ helper, a server URL is matched to the section whose user,
+// host and port (or socket) give the same URL, whatever its name. A new
+// section is named after the URL as sent by MySQL Shell, and list returns
+// that name, so that Shell gets back the URL it stored.
+//
+// Store and Erase hold a lock on the file <file>.lock while they rewrite
+// the file, so that concurrent calls don't lose writes.
+package secretstore
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// SecretTypePassword is the only type of secret supported, like the C++
+// helper.
+const SecretTypePassword = "password"
+
+// Version is printed by the version command.
+const Version = "mysql-secret-store-login-path Ver 1.0 (github.com/dolmen-go/mylogin)"
+
+// ID identifies a secret.
+type ID struct {
+	ServerURL  string `json:"ServerURL"`
+	SecretType string `json:"SecretType"`
+}
+
+// Secret is a secret with its ID.
+type Secret struct {
+	ID
+	Secret string `json:"Secret"`
+}
+
+// ErrNotFound is returned by Get and Erase.
+var ErrNotFound = errors.New("could not find the secret")
+
+// ParseURL returns the Login for a server URL of MySQL Shell:
+//
+//	user@host
+//	user@host:port
+//	user@[::1]:port
+//	user@(/path/to/socket)
+//	user@/path%2Fto%2Fsocket
+//
+// The user (and the socket of the second form) is percent-decoded. A socket
+// URL gives host "localhost".
+func ParseURL(serverURL string) (*mylogin.Login, error) {
+	i := strings.LastIndexByte(serverURL, '@')
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid URL %q: user expected", serverURL)
+	}
+	user, err := url.PathUnescape(serverURL[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %v", serverURL, err)
+	}
+	login := &mylogin.Login{User: &user}
+	target := serverURL[i+1:]
+	switch {
+	case target == "":
+		return nil, fmt.Errorf("invalid URL %q: host expected", serverURL)
+	case target[0] == '(' && target[len(target)-1] == ')':
+		socket := target[1 : len(target)-1]
+		login.Socket = &socket
+	case target[0] == '/' || target[0] == '.':
+		socket, err := url.PathUnescape(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL %q: %v", serverURL, err)
+		}
+		login.Socket = &socket
+	default:
+		host, port := target, ""
+		if h, p, err := net.SplitHostPort(target); err == nil {
+			host, port = h, p
+		} else if strings.HasPrefix(target, "[") && strings.HasSuffix(target, "]") {
+			host = target[1 : len(target)-1]
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid URL %q: host expected", serverURL)
+		}
+		login.Host = &host
+		if port != "" {
+			login.Port = &port
+		}
+		return login, nil
+	}
+	localhost := "localhost"
+	login.Host = &localhost
+	return login, nil
+}
+
+// URL returns the server URL of MySQL Shell for l, or "" if the user or
+// both the host and socket are missing. The socket has precedence over the
+// host and port, like with the MySQL client on localhost.
+func URL(l *mylogin.Login) string {
+	if l == nil || l.User == nil || (l.Host == nil && l.Socket == nil) {
+		return ""
+	}
+	user := escape(*l.User)
+	if l.Socket != nil && (l.Host == nil || *l.Host == "localhost") {
+		return user + "@(" + *l.Socket + ")"
+	}
+	host := *l.Host
+	if strings.IndexByte(host, ':') >= 0 {
+		host = "[" + host + "]"
+	}
+	if l.Port != nil {
+		return user + "@" + host + ":" + *l.Port
+	}
+	return user + "@" + host
+}
+
+// escape percent-encodes s, except the unreserved characters of RFC 3986.
+// The result is decoded by url.PathUnescape.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// normalizeURL returns the URL in the form returned by URL.
+func normalizeURL(serverURL string) (string, error) {
+	login, err := ParseURL(serverURL)
+	if err != nil {
+		return "", err
+	}
+	return URL(login), nil
+}
+
+func checkType(secretType string) error {
+	if secretType != SecretTypePassword {
+		return fmt.Errorf("unsupported secret type %q", secretType)
+	}
+	return nil
+}
+
+// Store stores secrets in a mylogin.cnf file.
+type Store struct {
+	// File is the path of the mylogin.cnf file. Default: mylogin.DefaultFile().
+	File string
+}
+
+func (s *Store) filename() string {
+	if s.File == "" {
+		return mylogin.DefaultFile()
+	}
+	return s.File
+}
+
+// read reads the file. A missing file is empty, with a new key.
+func (s *Store) read() (mylogin.File, mylogin.Sections, error) {
+	file, err := mylogin.ReadFile(s.filename())
+	switch {
+	case err == nil:
+		sections, err := mylogin.Parse(file.PlainText())
+		return file, sections.Normalize(), err
+	case os.IsNotExist(err):
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mylogin.NewFile(key, nil, nil), nil, nil
+	default:
+		return nil, nil, err
+	}
+}
+
+// lock locks the file for the duration of a read and a write. As WriteFile
+// replaces the file, the lock is held on a separate file (see lockFile).
+func (s *Store) lock() (unlock func() error, err error) {
+	return lockFile(s.filename() + ".lock")
+}
+
+func (s *Store) write(file mylogin.File, sections mylogin.Sections) error {
+	return mylogin.WriteFile(s.filename(), mylogin.NewFile(file.Key(), file.ByteOrder(), sections))
+}
+
+// find returns the index of the section for the URL, or -1. The section
+// named after the URL as sent by MySQL Shell has precedence.
+func find(sections mylogin.Sections, serverURL string) (int, error) {
+	normalized, err := normalizeURL(serverURL)
+	if err != nil {
+		return -1, err
+	}
+	found := -1
+	for i := range sections {
+		if URL(&sections[i].Login) == normalized {
+			if sections[i].Name == serverURL {
+				return i, nil
+			}
+			if found < 0 {
+				found = i
+			}
+		}
+	}
+	return found, nil
+}
+
+// sectionURL returns the URL to list for the section: its name if it is a
+// URL for the section (as stored by MySQL Shell), or the URL of its login.
+func sectionURL(section *mylogin.Section) string {
+	serverURL := URL(&section.Login)
+	if serverURL == "" {
+		return ""
+	}
+	if normalized, err := normalizeURL(section.Name); err == nil && normalized == serverURL {
+		return section.Name
+	}
+	return serverURL
+}
+
+// Store sets the password of the section that matches the URL, or creates
+// a section named after the URL.
+func (s *Store) Store(secret *Secret) error {
+	if err := checkType(secret.SecretType); err != nil {
+		return err
+	}
+	login, err := ParseURL(secret.ServerURL)
+	if err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, sections, err := s.read()
+	if err != nil {
+		return err
+	}
+	i, err := find(sections, secret.ServerURL)
+	if err != nil {
+		return err
+	}
+	password := secret.Secret
+	if i >= 0 {
+		sections[i].Login.Password = &password
+	} else {
+		login.Password = &password
+		sections.Set(mylogin.Section{Name: secret.ServerURL, Login: *login})
+	}
+	return s.write(file, sections)
+}
+
+// Get returns the password of the section that matches the URL.
+func (s *Store) Get(id *ID) (*Secret, error) {
+	if err := checkType(id.SecretType); err != nil {
+		return nil, err
+	}
+	_, sections, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	i, err := find(sections, id.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || sections[i].Login.Password == nil {
+		return nil, ErrNotFound
+	}
+	return &Secret{ID: *id, Secret: *sections[i].Login.Password}, nil
+}
+
+// Erase removes the section that matches the URL, like the C++ helper
+// does.
+func (s *Store) Erase(id *ID) error {
+	if err := checkType(id.SecretType); err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, sections, err := s.read()
+	if err != nil {
+		return err
+	}
+	i, err := find(sections, id.ServerURL)
+	if err != nil {
+		return err
+	}
+	if i < 0 || sections[i].Login.Password == nil {
+		return ErrNotFound
+	}
+	return s.write(file, append(sections[:i:i], sections[i+1:]...))
+}
+
+// List returns the IDs of the sections that have a URL and a password. The
+// URL is the name of the section if it was stored by MySQL Shell (see
+// Store).
+func (s *Store) List() ([]ID, error) {
+	_, sections, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	ids := []ID{}
+	for i := range sections {
+		if serverURL := sectionURL(&sections[i]); serverURL != "" && sections[i].Login.Password != nil {
+			ids = append(ids, ID{ServerURL: serverURL, SecretType: SecretTypePassword})
+		}
+	}
+	return ids, nil
+}
+
+// Run runs the command of the helper protocol given in args (without the
+// program name), with the JSON input read from stdin and the output written
+// to stdout. It returns the exit code: on failure the error message is
+// written to stdout, as MySQL Shell expects, and the exit code is 1.
+func Run(s *Store, args []string, stdin io.Reader, stdout io.Writer) int {
+	if err := run(s, args, stdin, stdout); err != nil {
+		fmt.Fprintln(stdout, err)
+		return 1
+	}
+	return 0
+}
+
+func run(s *Store, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: store|get|erase|list|version")
+	}
+	enc := json.NewEncoder(stdout)
+	switch args[0] {
+	case "store":
+		var secret Secret
+		if err := json.NewDecoder(stdin).Decode(&secret); err != nil {
+			return err
+		}
+		return s.Store(&secret)
+	case "get":
+		var id ID
+		if err := json.NewDecoder(stdin).Decode(&id); err != nil {
+			return err
+		}
+		secret, err := s.Get(&id)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(secret)
+	case "erase":
+		var id ID
+		if err := json.NewDecoder(stdin).Decode(&id); err != nil {
+			return err
+		}
+		return s.Erase(&id)
+	case "list":
+		ids, err := s.List()
+		if err != nil {
+			return err
+		}
+		return enc.Encode(ids)
+	case "version":
+		_, err := fmt.Fprintln(stdout, Version)
+		return err
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
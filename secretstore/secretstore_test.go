@@ -0,0 +1,188 @@
+package secretstore_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/secretstore"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestURL(t *testing.T) {
+	for _, test := range []struct {
+		url        string
+		login      mylogin.Login
+		normalized string
+	}{
+		{"root@localhost", mylogin.Login{User: stringPtr("root"), Host: stringPtr("localhost")}, ""},
+		{"root@db:3307", mylogin.Login{User: stringPtr("root"), Host: stringPtr("db"), Port: stringPtr("3307")}, ""},
+		{"root@[::1]:3306", mylogin.Login{User: stringPtr("root"), Host: stringPtr("::1"), Port: stringPtr("3306")}, ""},
+		{"root@[::1]", mylogin.Login{User: stringPtr("root"), Host: stringPtr("::1")}, ""},
+		{"a%40b@db", mylogin.Login{User: stringPtr("a@b"), Host: stringPtr("db")}, ""},
+		{"a@b@db", mylogin.Login{User: stringPtr("a@b"), Host: stringPtr("db")}, "a%40b@db"},
+		{"a%2Bb%20c@db", mylogin.Login{User: stringPtr("a+b c"), Host: stringPtr("db")}, ""},
+		{"a+b@db", mylogin.Login{User: stringPtr("a+b"), Host: stringPtr("db")}, "a%2Bb@db"},
+		{"root@(/tmp/mysql.sock)", mylogin.Login{User: stringPtr("root"), Host: stringPtr("localhost"), Socket: stringPtr("/tmp/mysql.sock")}, ""},
+		{"root@/tmp%2Fmysql.sock", mylogin.Login{User: stringPtr("root"), Host: stringPtr("localhost"), Socket: stringPtr("/tmp/mysql.sock")}, "root@(/tmp/mysql.sock)"},
+	} {
+		login, err := secretstore.ParseURL(test.url)
+		if err != nil {
+			t.Errorf("%q: %v", test.url, err)
+			continue
+		}
+		if !reflect.DeepEqual(*login, test.login) {
+			t.Errorf("%q: got %#v, expected %#v", test.url, login, test.login)
+		}
+		if test.normalized == "" {
+			test.normalized = test.url
+		}
+		if got := secretstore.URL(login); got != test.normalized {
+			t.Errorf("%q: URL: got %q, expected %q", test.url, got, test.normalized)
+		}
+	}
+
+	for _, u := range []string{"", "db", "@db", "root@", "root@:3306"} {
+		if _, err := secretstore.ParseURL(u); err == nil {
+			t.Errorf("%q: error expected", u)
+		}
+	}
+
+	if got := secretstore.URL(&mylogin.Login{Host: stringPtr("db")}); got != "" {
+		t.Errorf("no user: got %q", got)
+	}
+}
+
+func run(t *testing.T, store *secretstore.Store, command, input string) (string, int) {
+	var out bytes.Buffer
+	code := secretstore.Run(store, []string{command}, strings.NewReader(input), &out)
+	return out.String(), code
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &secretstore.Store{File: filepath.Join(dir, ".mylogin.cnf")}
+
+	// An existing section is matched by URL, whatever its name
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mylogin.WriteFile(store.File, mylogin.NewFile(key, nil, mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("me")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("admin"), Host: stringPtr("db"), Port: stringPtr("3306")}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := run(t, store, "list", ""); code != 0 || out != "[]\n" {
+		t.Errorf("list: %d %q", code, out)
+	}
+	if out, code := run(t, store, "get", `{"ServerURL":"admin@db:3306","SecretType":"password"}`); code != 1 || out == "" {
+		t.Errorf("get of a missing secret: %d %q", code, out)
+	}
+	for _, input := range []string{
+		`{"ServerURL":"admin@db:3306","SecretType":"password","Secret":"s1"}`,
+		`{"ServerURL":"root@(/tmp/mysql.sock)","SecretType":"password","Secret":"s2"}`,
+		`{"ServerURL":"app@/var%2Frun%2Fmysqld.sock","SecretType":"password","Secret":"s3"}`,
+	} {
+		if out, code := run(t, store, "store", input); code != 0 || out != "" {
+			t.Errorf("store: %d %q", code, out)
+		}
+	}
+	if out, code := run(t, store, "get", `{"ServerURL":"admin@db:3306","SecretType":"password"}`); code != 0 || out != `{"ServerURL":"admin@db:3306","SecretType":"password","Secret":"s1"}`+"\n" {
+		t.Errorf("get: %d %q", code, out)
+	}
+	// The URLs are listed as MySQL Shell sent them
+	if out, code := run(t, store, "list", ""); code != 0 || out != `[{"ServerURL":"admin@db:3306","SecretType":"password"},{"ServerURL":"root@(/tmp/mysql.sock)","SecretType":"password"},{"ServerURL":"app@/var%2Frun%2Fmysqld.sock","SecretType":"password"}]`+"\n" {
+		t.Errorf("list: %d %q", code, out)
+	}
+
+	sections, err := mylogin.ReadSections(store.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("me")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("admin"), Password: stringPtr("s1"), Host: stringPtr("db"), Port: stringPtr("3306")}},
+		{Name: "root@(/tmp/mysql.sock)", Login: mylogin.Login{User: stringPtr("root"), Password: stringPtr("s2"), Host: stringPtr("localhost"), Socket: stringPtr("/tmp/mysql.sock")}},
+		{Name: "app@/var%2Frun%2Fmysqld.sock", Login: mylogin.Login{User: stringPtr("app"), Password: stringPtr("s3"), Host: stringPtr("localhost"), Socket: stringPtr("/var/run/mysqld.sock")}},
+	}
+	if !reflect.DeepEqual(sections, expected) {
+		t.Errorf("got %#v, expected %#v", sections, expected)
+	}
+
+	if out, code := run(t, store, "erase", `{"ServerURL":"root@/tmp%2Fmysql.sock","SecretType":"password"}`); code != 0 || out != "" {
+		t.Errorf("erase: %d %q", code, out)
+	}
+	if out, code := run(t, store, "erase", `{"ServerURL":"root@/tmp%2Fmysql.sock","SecretType":"password"}`); code != 1 {
+		t.Errorf("erase of a missing secret: %d %q", code, out)
+	}
+	if out, code := run(t, store, "erase", `{"ServerURL":"app@(/var/run/mysqld.sock)","SecretType":"password"}`); code != 0 || out != "" {
+		t.Errorf("erase: %d %q", code, out)
+	}
+	if out, code := run(t, store, "list", ""); code != 0 || out != `[{"ServerURL":"admin@db:3306","SecretType":"password"}]`+"\n" {
+		t.Errorf("list: %d %q", code, out)
+	}
+
+	if out, code := run(t, store, "store", `{"ServerURL":"admin@db:3306","SecretType":"generic","Secret":"x"}`); code != 1 {
+		t.Errorf("generic secret: %d %q", code, out)
+	}
+	if out, code := run(t, store, "version", ""); code != 0 || out != secretstore.Version+"\n" {
+		t.Errorf("version: %d %q", code, out)
+	}
+	if out, code := run(t, store, "bad", ""); code != 1 {
+		t.Errorf("bad command: %d %q", code, out)
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &secretstore.Store{File: filepath.Join(dir, ".mylogin.cnf")}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			secret := secretstore.Secret{
+				ID:     secretstore.ID{ServerURL: fmt.Sprintf("user%d@db", i), SecretType: secretstore.SecretTypePassword},
+				Secret: "s",
+			}
+			if err := store.Store(&secret); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// No write is lost
+	ids, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != n {
+		t.Errorf("got %d secrets, expected %d: %v", len(ids), n, ids)
+	}
+}
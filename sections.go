@@ -1,5 +1,13 @@
 package mylogin
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+)
+
 // Section represents one section of the plaintext content of mylogin.cnf.
 type Section struct {
 	Name  string `json:"name"`
@@ -9,14 +17,83 @@ type Section struct {
 // Sections represents the structured content on the plaintext of mylogin.cnf.
 type Sections []Section
 
-// Login returns the Login from the section with the given name.
+// DuplicatePolicy defines the values used when several sections have the
+// same name, which may happen after hand edits or concatenation of files.
+// mysql_config_editor never writes duplicates.
+type DuplicatePolicy int
+
+const (
+	// DuplicateMerge merges the sections in the order of the file: for each
+	// option the last section that has a value has the precedence. This is
+	// how the MySQL client reads the file, as any option file.
+	DuplicateMerge DuplicatePolicy = iota
+	// DuplicateFirst uses only the first section.
+	DuplicateFirst
+	// DuplicateLast uses only the last section.
+	DuplicateLast
+)
+
+// Login returns the Login from the section with the given name, or nil.
+// Duplicate sections are merged like the MySQL client does (see
+// DuplicatePolicy).
 func (sections Sections) Login(section string) *Login {
+	return sections.Lookup(section, DuplicateMerge)
+}
+
+// Lookup returns the Login from the section with the given name, or nil.
+// policy defines how duplicate sections are handled.
+func (sections Sections) Lookup(section string, policy DuplicatePolicy) *Login {
+	var login *Login
+	for i := range sections {
+		if sections[i].Name != section {
+			continue
+		}
+		switch {
+		case login == nil:
+			l := sections[i].Login
+			login = &l
+			if policy == DuplicateFirst {
+				return login
+			}
+		case policy == DuplicateLast:
+			*login = sections[i].Login
+		default:
+			login.Merge(&sections[i].Login)
+		}
+	}
+	return login
+}
+
+// Normalize returns a copy of the sections where duplicate sections are
+// folded into the first one, merged like the MySQL client does (see
+// DuplicateMerge). The order of the sections is preserved and the output of
+// WriteTo then has the options in a fixed order. Use Normalize to clean up
+// a file before rewriting it.
+func (sections Sections) Normalize() Sections {
+	normalized := make(Sections, 0, len(sections))
+	index := make(map[string]int, len(sections))
 	for _, s := range sections {
-		if s.Name == section {
-			return &s.Login
+		if i, seen := index[s.Name]; seen {
+			normalized[i].Login.Merge(&s.Login)
+			continue
 		}
+		index[s.Name] = len(normalized)
+		normalized = append(normalized, s)
 	}
-	return nil
+	return normalized
+}
+
+// Set replaces the first section with the same name as s, or appends s if
+// there is no such section. Duplicates of that section are left unchanged,
+// so use Normalize first.
+func (sections *Sections) Set(s Section) {
+	for i := range *sections {
+		if (*sections)[i].Name == s.Name {
+			(*sections)[i] = s
+			return
+		}
+	}
+	*sections = append(*sections, s)
 }
 
 // Merge returns a single Login which is the result of the ordered merge
@@ -39,3 +116,111 @@ func (sections Sections) Merge(sectionNames []string) (login *Login) {
 
 	return
 }
+
+// WriteTo writes the plaintext content of a mylogin.cnf file holding the
+// sections. The output can be read back with Parse.
+func (sections Sections) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	for i := range sections {
+		b.WriteByte('[')
+		b.WriteString(sections[i].Name)
+		b.WriteString("]\n")
+		sections[i].Login.writeTo(&b)
+	}
+	return b.WriteTo(w)
+}
+
+// ExtractOptions are the options of Sections.Extract.
+type ExtractOptions struct {
+	// Rename maps original section names to new names.
+	Rename map[string]string
+	// DropPasswords removes passwords from the extracted sections.
+	DropPasswords bool
+}
+
+// Extract returns a copy of the sections with a name that matches one of
+// the patterns (see Select for the syntax), in the order of the file.
+// An error is returned if a pattern doesn't match any section.
+func (sections Sections) Extract(patterns []string, opts *ExtractOptions) (Sections, error) {
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+	matchers := make([]func(string) bool, len(patterns))
+	for i, pattern := range patterns {
+		m, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = m
+	}
+	matched := make([]bool, len(patterns))
+	var extracted Sections
+	for _, s := range sections {
+		var found bool
+		for i, match := range matchers {
+			if match(s.Name) {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		if name, ok := opts.Rename[s.Name]; ok {
+			s.Name = name
+		}
+		if opts.DropPasswords {
+			s.Login.Password = nil
+		}
+		extracted = append(extracted, s)
+	}
+	for i, ok := range matched {
+		if !ok {
+			return nil, fmt.Errorf("no section matches %q", patterns[i])
+		}
+	}
+	return extracted, nil
+}
+
+// Select returns the sections with a name that matches pattern, in the
+// order of the file.
+//
+// The pattern is a glob (see [path.Match]) such as "prod-*", or a regular
+// expression (see [regexp]) if enclosed in slashes, such as
+// "/^prod-(ro|rw)$/". A name equal to pattern always matches.
+func (sections Sections) Select(pattern string) (Sections, error) {
+	match, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var selected Sections
+	for _, s := range sections {
+		if match(s.Name) {
+			selected = append(selected, s)
+		}
+	}
+	return selected, nil
+}
+
+func compilePattern(pattern string) (func(string) bool, error) {
+	if len(pattern) >= 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", pattern, err)
+		}
+		return func(name string) bool {
+			return name == pattern || re.MatchString(name)
+		}, nil
+	}
+	// Check the syntax
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("%q: %v", pattern, err)
+	}
+	return func(name string) bool {
+		if name == pattern {
+			return true
+		}
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, nil
+}
@@ -0,0 +1,270 @@
+package mylogin_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestSectionsWriteTo(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{
+			User:     stringPtr("dolmen"),
+			Password: stringPtr(`p"a\s s=w\n` + "\t\n"),
+			Host:     stringPtr("localhost"),
+		}},
+		{Name: "empty"},
+		{Name: "prod", Login: mylogin.Login{
+			Port:   stringPtr("3307"),
+			Socket: stringPtr("/var/run/my sql.sock"),
+		}},
+	}
+
+	var b bytes.Buffer
+	if _, err := sections.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s", b.Bytes())
+
+	got, err := mylogin.Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sections) {
+		t.Errorf("got %#v, expected %#v", got, sections)
+	}
+}
+
+func TestNewFile(t *testing.T) {
+	key, err := mylogin.NewKey(rand.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen")}},
+	}
+
+	var b bytes.Buffer
+	if err = mylogin.Encode(&b, mylogin.NewFile(key, nil, sections)); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := mylogin.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Key() != key {
+		t.Errorf("key: got %X, expected %X", f.Key(), key)
+	}
+	got, err := mylogin.Parse(f.PlainText())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sections) {
+		t.Errorf("got %#v, expected %#v", got, sections)
+	}
+}
+
+func TestSectionsExtract(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("me")}},
+		{Name: "prod-ro", Login: mylogin.Login{User: stringPtr("ro"), Password: stringPtr("secret")}},
+		{Name: "prod-rw", Login: mylogin.Login{User: stringPtr("rw"), Password: stringPtr("secret")}},
+		{Name: "test", Login: mylogin.Login{User: stringPtr("test")}},
+	}
+
+	got, err := sections.Extract([]string{"test", "prod-*"}, &mylogin.ExtractOptions{
+		Rename:        map[string]string{"prod-ro": "client"},
+		DropPasswords: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("ro")}},
+		{Name: "prod-rw", Login: mylogin.Login{User: stringPtr("rw")}},
+		{Name: "test", Login: mylogin.Login{User: stringPtr("test")}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v, expected %#v", got, expected)
+	}
+	// The source must not be modified
+	if *sections[1].Login.Password != "secret" {
+		t.Error("source modified")
+	}
+
+	if _, err = sections.Extract([]string{"dev-*"}, nil); err == nil {
+		t.Error("error expected for pattern without match")
+	}
+	if _, err = sections.Extract([]string{"["}, nil); err == nil {
+		t.Error("error expected for bad pattern")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, ".mylogin.cnf")
+
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen")}},
+	}
+	for i := 0; i < 2; i++ { // Create, then replace
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := mylogin.ReadSections(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, sections) {
+			t.Errorf("got %#v, expected %#v", got, sections)
+		}
+		sections[0].Name = "other"
+	}
+
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("mode: got %v, expected 0600", fi.Mode().Perm())
+		}
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("%d files left in directory", len(files))
+	}
+}
+
+func TestSectionsSet(t *testing.T) {
+	var sections mylogin.Sections
+	sections.Set(mylogin.Section{Name: "client", Login: mylogin.Login{User: stringPtr("a")}})
+	sections.Set(mylogin.Section{Name: "prod", Login: mylogin.Login{User: stringPtr("b")}})
+	sections.Set(mylogin.Section{Name: "client", Login: mylogin.Login{Host: stringPtr("c")}})
+
+	expected := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{Host: stringPtr("c")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("b")}},
+	}
+	if !reflect.DeepEqual(sections, expected) {
+		t.Errorf("got %#v, expected %#v", sections, expected)
+	}
+}
+
+func TestSectionsDuplicates(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("a"), Host: stringPtr("h1")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("b")}},
+		{Name: "client", Login: mylogin.Login{Host: stringPtr("h2"), Port: stringPtr("3307")}},
+	}
+
+	for _, test := range []struct {
+		policy   mylogin.DuplicatePolicy
+		expected mylogin.Login
+	}{
+		{mylogin.DuplicateMerge, mylogin.Login{User: stringPtr("a"), Host: stringPtr("h2"), Port: stringPtr("3307")}},
+		{mylogin.DuplicateFirst, mylogin.Login{User: stringPtr("a"), Host: stringPtr("h1")}},
+		{mylogin.DuplicateLast, mylogin.Login{Host: stringPtr("h2"), Port: stringPtr("3307")}},
+	} {
+		got := sections.Lookup("client", test.policy)
+		if got == nil || !reflect.DeepEqual(*got, test.expected) {
+			t.Errorf("policy %d: got %v, expected %v", test.policy, got, &test.expected)
+		}
+	}
+	if got := sections.Login("client"); !reflect.DeepEqual(got, sections.Lookup("client", mylogin.DuplicateMerge)) {
+		t.Errorf("Login: got %v", got)
+	}
+	if got := sections.Lookup("dev", mylogin.DuplicateMerge); got != nil {
+		t.Errorf("dev: got %v, expected nil", got)
+	}
+
+	normalized := sections.Normalize()
+	expected := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("a"), Host: stringPtr("h2"), Port: stringPtr("3307")}},
+		{Name: "prod", Login: mylogin.Login{User: stringPtr("b")}},
+	}
+	if !reflect.DeepEqual(normalized, expected) {
+		t.Errorf("Normalize: got %#v, expected %#v", normalized, expected)
+	}
+	// The original is unchanged
+	if sections[0].Login.Port != nil {
+		t.Error("Normalize modified its input")
+	}
+}
+
+func TestSectionsSelect(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client"},
+		{Name: "prod-ro"},
+		{Name: "prod-rw"},
+		{Name: "prod-admin"},
+		{Name: "[x]"},
+	}
+	for _, test := range []struct {
+		pattern  string
+		expected []string
+	}{
+		{"client", []string{"client"}},
+		{"prod-*", []string{"prod-ro", "prod-rw", "prod-admin"}},
+		{"prod-r?", []string{"prod-ro", "prod-rw"}},
+		{"/^prod-(ro|admin)$/", []string{"prod-ro", "prod-admin"}},
+		{"/w$/", []string{"prod-rw"}},
+		{"[x]", []string{"[x]"}},
+		{"dev", nil},
+	} {
+		selected, err := sections.Select(test.pattern)
+		if err != nil {
+			t.Errorf("%q: %v", test.pattern, err)
+			continue
+		}
+		var names []string
+		for _, s := range selected {
+			names = append(names, s.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%q: got %q, expected %q", test.pattern, names, test.expected)
+		}
+	}
+
+	for _, pattern := range []string{"[", "/(/"} {
+		if _, err := sections.Select(pattern); err == nil {
+			t.Errorf("%q: error expected", pattern)
+		}
+	}
+}
+
+func TestParseEmptyLines(t *testing.T) {
+	got, err := mylogin.Parse(strings.NewReader("\n[client]\n\nuser = me\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mylogin.Sections{{Name: "client", Login: mylogin.Login{User: stringPtr("me")}}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v, expected %#v", got, expected)
+	}
+}
+
+func TestParseInvalidHeader(t *testing.T) {
+	for _, input := range []string{"[\n", "[client\nuser = me\n", "[client]\n[prod"} {
+		if _, err := mylogin.Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("%q: error expected", input)
+		}
+	}
+}
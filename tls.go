@@ -0,0 +1,178 @@
+package mylogin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// SSL modes of the MySQL client (--ssl-mode).
+const (
+	SSLModeDisabled       = "DISABLED"
+	SSLModePreferred      = "PREFERRED"
+	SSLModeRequired       = "REQUIRED"
+	SSLModeVerifyCA       = "VERIFY_CA"
+	SSLModeVerifyIdentity = "VERIFY_IDENTITY"
+)
+
+// TLSOptions are the TLS options of the MySQL client. Login paths can't
+// hold them, but plain option files can (see ReadTLSOptions).
+type TLSOptions struct {
+	Mode   string `json:"ssl-mode,omitempty"`   // See SSLMode* constants
+	CA     string `json:"ssl-ca,omitempty"`     // CA certificates file (PEM)
+	CAPath string `json:"ssl-capath,omitempty"` // Directory of CA certificates (PEM)
+	Cert   string `json:"ssl-cert,omitempty"`   // Client certificate file (PEM)
+	Key    string `json:"ssl-key,omitempty"`    // Client key file (PEM)
+}
+
+// ReadTLSOptions reads the TLS options from the given groups of a plain
+// text option file (see ReadOptionFile). The result is nil if the file has
+// no TLS options.
+func ReadTLSOptions(filename string, groups []string) (*TLSOptions, error) {
+	options, err := ReadOptionFile(filename, groups)
+	if err != nil {
+		return nil, err
+	}
+	o := &TLSOptions{
+		Mode:   strings.ToUpper(options["ssl-mode"]),
+		CA:     options["ssl-ca"],
+		CAPath: options["ssl-capath"],
+		Cert:   options["ssl-cert"],
+		Key:    options["ssl-key"],
+	}
+	if *o == (TLSOptions{}) {
+		return nil, nil
+	}
+	return o, nil
+}
+
+// EffectiveMode returns the SSL mode, with the defaults of the MySQL
+// client: VERIFY_CA if a CA is set, else PREFERRED.
+func (o *TLSOptions) EffectiveMode() string {
+	switch {
+	case o == nil:
+		return SSLModePreferred
+	case o.Mode != "":
+		return strings.ToUpper(o.Mode)
+	case o.CA != "" || o.CAPath != "":
+		return SSLModeVerifyCA
+	default:
+		return SSLModePreferred
+	}
+}
+
+// Config builds the configuration of a TLS client that follows the SSL
+// mode (see EffectiveMode):
+//
+//   - DISABLED: nil (no TLS).
+//   - PREFERRED, REQUIRED: encryption without verification of the server
+//     certificate. As a TLS configuration can't express the fall back to an
+//     unencrypted connection, PREFERRED is the same as REQUIRED here (see
+//     package [github.com/dolmen-go/mylogin/mysqltls] for the driver).
+//   - VERIFY_CA: the server certificate must be signed by the CA.
+//   - VERIFY_IDENTITY: VERIFY_CA and the server certificate must also
+//     match the host name. ServerName is left empty, to be set by the
+//     driver from the host.
+//
+// The system CAs are used if no CA is set.
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	mode := o.EffectiveMode()
+	if mode == SSLModeDisabled {
+		return nil, nil
+	}
+	if o == nil {
+		o = &TLSOptions{}
+	}
+	cfg := &tls.Config{}
+
+	if o.Cert != "" || o.Key != "" {
+		if o.Cert == "" || o.Key == "" {
+			return nil, errors.New("ssl-cert and ssl-key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.Cert, o.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch mode {
+	case SSLModePreferred, SSLModeRequired:
+		cfg.InsecureSkipVerify = true
+		return cfg, nil
+	case SSLModeVerifyCA, SSLModeVerifyIdentity:
+	default:
+		return nil, fmt.Errorf("unknown ssl-mode %q", o.Mode)
+	}
+
+	if o.CA != "" || o.CAPath != "" {
+		roots, err := o.certPool()
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = roots
+	}
+	if mode == SSLModeVerifyIdentity {
+		return cfg, nil
+	}
+
+	// VERIFY_CA: verify the chain, but not the host name
+	cfg.InsecureSkipVerify = true
+	roots := cfg.RootCAs
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no server certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+	return cfg, nil
+}
+
+// certPool loads the CA certificates of CA and of the PEM files in CAPath.
+func (o *TLSOptions) certPool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if o.CA != "" {
+		pem, err := ioutil.ReadFile(o.CA)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificate", o.CA)
+		}
+	}
+	if o.CAPath != "" {
+		files, err := filepath.Glob(filepath.Join(o.CAPath, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			pem, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			// Files without certificates (such as keys) are ignored
+			pool.AppendCertsFromPEM(pem)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,167 @@
+package mylogin_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// testCert is a certificate generated for tests.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{cn}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writeFiles(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+"-cert.pem")
+	keyFile = filepath.Join(dir, name+"-key.pem")
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// handshake runs a TLS handshake between cfg and a server with cert.
+// It returns the number of client certificates seen by the server, and the
+// client error.
+func handshake(cfg *tls.Config, serverName string, cert *testCert, clientCA *x509.CertPool) (int, error) {
+	cfg = cfg.Clone()
+	cfg.ServerName = serverName
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+	server := tls.Server(s, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.der}, PrivateKey: cert.key}},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCA,
+	})
+	peers := make(chan int, 1)
+	go func() {
+		server.Handshake()
+		peers <- len(server.ConnectionState().PeerCertificates)
+		s.Close()
+	}()
+	err := tls.Client(c, cfg).Handshake()
+	c.Close()
+	return <-peers, err
+}
+
+func TestTLSOptionsConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "Test CA", nil)
+	otherCA := newTestCert(t, "Other CA", nil)
+	server := newTestCert(t, "db.example", ca)
+	client := newTestCert(t, "client", ca)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	otherCAFile, _ := otherCA.writeFiles(t, dir, "other-ca")
+	clientCert, clientKey := client.writeFiles(t, dir, "client")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	for _, test := range []struct {
+		opts       mylogin.TLSOptions
+		serverName string
+		ok         bool
+	}{
+		{mylogin.TLSOptions{Mode: "required"}, "db.example", true},
+		{mylogin.TLSOptions{Mode: "PREFERRED", CA: otherCAFile}, "db.example", true},
+		{mylogin.TLSOptions{CA: caFile}, "other.example", true}, // VERIFY_CA
+		{mylogin.TLSOptions{CA: otherCAFile}, "db.example", false},
+		{mylogin.TLSOptions{Mode: "VERIFY_CA", CAPath: dir}, "other.example", true},
+		{mylogin.TLSOptions{Mode: "VERIFY_IDENTITY", CA: caFile}, "db.example", true},
+		{mylogin.TLSOptions{Mode: "VERIFY_IDENTITY", CA: caFile}, "other.example", false},
+		{mylogin.TLSOptions{Mode: "VERIFY_IDENTITY", CA: otherCAFile}, "db.example", false},
+	} {
+		cfg, err := test.opts.Config()
+		if err != nil {
+			t.Errorf("%+v: %v", test.opts, err)
+			continue
+		}
+		_, err = handshake(cfg, test.serverName, server, clientCAs)
+		if (err == nil) != test.ok {
+			t.Errorf("%+v %s: got %v, expected success: %t", test.opts, test.serverName, err, test.ok)
+		}
+	}
+
+	// Client certificate
+	cfg, err := (&mylogin.TLSOptions{CA: caFile, Cert: clientCert, Key: clientKey}).Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peers, err := handshake(cfg, "db.example", server, clientCAs); err != nil || peers != 1 {
+		t.Errorf("client certificate: got %v, %d certificates", err, peers)
+	}
+
+	if cfg, err = (&mylogin.TLSOptions{Mode: "disabled", CA: caFile}).Config(); cfg != nil || err != nil {
+		t.Errorf("DISABLED: got %v, %v", cfg, err)
+	}
+	for _, opts := range []mylogin.TLSOptions{
+		{Mode: "VERIFY_ALL"},
+		{Cert: clientCert},
+		{CA: filepath.Join(dir, "missing.pem")},
+	} {
+		if _, err = opts.Config(); err == nil {
+			t.Errorf("%+v: error expected", opts)
+		}
+	}
+}
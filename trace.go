@@ -0,0 +1,114 @@
+package mylogin
+
+import "io"
+
+// Source is the content of a login file with the line number of each
+// option, for tracing merges (see MergeTrace).
+type Source struct {
+	// File is the path of the file, or empty if unknown.
+	File     string
+	Sections Sections
+	// lines[i] maps the options of Sections[i] to their line number
+	lines []map[string]int
+}
+
+// ParseSource parses the plaintext content of a mylogin.cnf file, like
+// Parse, and records the line numbers of the options.
+// file is the path used in the traces.
+func ParseSource(file string, rd io.Reader) (*Source, error) {
+	src := &Source{File: file}
+	var err error
+	src.Sections, err = parse(rd, func(section int, option string, line int) {
+		for len(src.lines) <= section {
+			src.lines = append(src.lines, make(map[string]int))
+		}
+		src.lines[section][option] = line
+	})
+	if err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// ReadSource reads a mylogin.cnf file like ReadSections and records the
+// line numbers of the options (see ParseSource).
+func ReadSource(filename string) (*Source, error) {
+	return (&ReadOptions{}).ReadSource(filename)
+}
+
+// ReadSource is like the ReadSource function with options.
+func (o *ReadOptions) ReadSource(filename string) (*Source, error) {
+	f, err := o.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSource(filename, f)
+}
+
+// readSource decodes a mylogin.cnf file and parses it with ParseSource.
+func readSource(file string, r io.Reader) (*Source, error) {
+	f, err := Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSource(file, f.PlainText())
+}
+
+// Trace is the provenance of an option value considered in a merge.
+type Trace struct {
+	Option  string `json:"option"`
+	Value   string `json:"value"`
+	File    string `json:"file,omitempty"`
+	Section string `json:"section"`
+	// Line is the line number in the plaintext, which is also the
+	// number of the chunk in the file (starting at 1).
+	Line int `json:"line"`
+	// Overridden is true if the value is replaced by a later one.
+	Overridden bool `json:"overridden"`
+}
+
+// MergeTrace is like Sections.Merge over multiple sources, and also returns
+// the values considered, in the order of the merge: for each section name,
+// the sections of each source in order. Duplicate sections are merged (see
+// DuplicateMerge). For each option the last value has the precedence.
+func MergeTrace(sectionNames []string, sources ...*Source) (login *Login, traces []Trace) {
+	last := make(map[string]int) // option => index in traces
+	for _, name := range sectionNames {
+		if name == "" {
+			name = DefaultSection
+		}
+		for _, src := range sources {
+			for i := range src.Sections {
+				s := &src.Sections[i]
+				if s.Name != name || s.Login.IsEmpty() {
+					continue
+				}
+				if login == nil {
+					login = new(Login)
+				}
+				login.Merge(&s.Login)
+				for _, opt := range s.Login.options() {
+					if opt.value == nil {
+						continue
+					}
+					if j, ok := last[opt.name]; ok {
+						traces[j].Overridden = true
+					}
+					last[opt.name] = len(traces)
+					t := Trace{
+						Option:  opt.name,
+						Value:   *opt.value,
+						File:    src.File,
+						Section: s.Name,
+					}
+					if i < len(src.lines) {
+						t.Line = src.lines[i][opt.name]
+					}
+					traces = append(traces, t)
+				}
+			}
+		}
+	}
+	return
+}
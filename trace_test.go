@@ -0,0 +1,59 @@
+package mylogin_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestMergeTrace(t *testing.T) {
+	src1, err := mylogin.ParseSource("a.cnf", strings.NewReader(`[client]
+user = "dolmen"
+host = "localhost"
+
+[prod]
+host = "db"
+port = "3307"
+[client]
+password = "secret"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src2, err := mylogin.ParseSource("b.cnf", strings.NewReader(`[prod]
+port = "3308"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"", "prod"}
+	login, traces := mylogin.MergeTrace(names, src1, src2)
+	if login.DSN() != "dolmen:secret@tcp(db:3308)/" {
+		t.Errorf("got %s", login.DSN())
+	}
+
+	expected := []mylogin.Trace{
+		{Option: "user", Value: "dolmen", File: "a.cnf", Section: "client", Line: 2},
+		{Option: "host", Value: "localhost", File: "a.cnf", Section: "client", Line: 3, Overridden: true},
+		{Option: "password", Value: "secret", File: "a.cnf", Section: "client", Line: 9},
+		{Option: "host", Value: "db", File: "a.cnf", Section: "prod", Line: 6},
+		{Option: "port", Value: "3307", File: "a.cnf", Section: "prod", Line: 7, Overridden: true},
+		{Option: "port", Value: "3308", File: "b.cnf", Section: "prod", Line: 2},
+	}
+	if !reflect.DeepEqual(traces, expected) {
+		t.Errorf("got:\n%+v\nexpected:\n%+v", traces, expected)
+	}
+
+	// Same result as Sections.Merge with a single source
+	login, _ = mylogin.MergeTrace(names, src1)
+	if !reflect.DeepEqual(login, src1.Sections.Merge(names)) {
+		t.Errorf("got %v, expected %v", login, src1.Sections.Merge(names))
+	}
+
+	if login, traces = mylogin.MergeTrace([]string{"dev"}, src1); login != nil || traces != nil {
+		t.Errorf("dev: got %v %v", login, traces)
+	}
+}
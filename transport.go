@@ -0,0 +1,105 @@
+package mylogin
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// DefaultSockets are the usual locations of the Unix socket of the MySQL
+// server, in the order of lookup by Login.ResolveTransport.
+var DefaultSockets = []string{
+	"/var/run/mysqld/mysqld.sock", // Debian, Ubuntu
+	"/run/mysqld/mysqld.sock",
+	"/var/lib/mysql/mysql.sock", // Red Hat, Fedora
+	"/tmp/mysql.sock",           // MySQL default, macOS
+}
+
+// TransportOptions are the options of Login.ResolveTransport.
+type TransportOptions struct {
+	// Protocol is the --protocol option of the MySQL client: "tcp",
+	// "socket", or "" for the default behaviour.
+	Protocol string
+	// Sockets are the candidate socket paths. Default: DefaultSockets.
+	Sockets []string
+	// Stat is used to check that a socket exists. Default: os.Stat.
+	Stat func(name string) (os.FileInfo, error)
+	// Getenv is used to read MYSQL_UNIX_PORT. Default: os.Getenv.
+	Getenv func(key string) string
+}
+
+// ResolveTransport returns a copy of l with the transport resolved the way
+// the MySQL client does, so that DSN returns a connection on the same
+// transport as the mysql command:
+//
+//   - with Protocol "tcp", or a host that is not "localhost", TCP is used
+//     (the socket is dropped and the host defaults to "localhost").
+//   - otherwise (host is "localhost" or not set), the Unix socket is used
+//     (the host and port are dropped). The socket is the one set in l
+//     (usually from [client]), else $MYSQL_UNIX_PORT, else the first of
+//     Sockets that exists, else "/tmp/mysql.sock".
+//
+// On Windows the default protocol is TCP.
+func (l *Login) ResolveTransport(opts *TransportOptions) (*Login, error) {
+	if opts == nil {
+		opts = &TransportOptions{}
+	}
+	var resolved Login
+	if l != nil {
+		resolved = *l
+	}
+
+	protocol := opts.Protocol
+	switch protocol {
+	case "":
+		if runtime.GOOS == "windows" || (resolved.Host != nil && *resolved.Host != "localhost") {
+			protocol = "tcp"
+		} else {
+			protocol = "socket"
+		}
+	case "tcp", "socket":
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+
+	if protocol == "tcp" {
+		resolved.Socket = nil
+		if resolved.Host == nil {
+			host := "localhost"
+			resolved.Host = &host
+		}
+		return &resolved, nil
+	}
+
+	resolved.Host = nil
+	resolved.Port = nil
+	if resolved.Socket == nil {
+		socket := opts.defaultSocket()
+		resolved.Socket = &socket
+	}
+	return &resolved, nil
+}
+
+func (opts *TransportOptions) defaultSocket() string {
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if socket := getenv("MYSQL_UNIX_PORT"); socket != "" {
+		return socket
+	}
+	stat := opts.Stat
+	if stat == nil {
+		stat = os.Stat
+	}
+	sockets := opts.Sockets
+	if sockets == nil {
+		sockets = DefaultSockets
+	}
+	for _, socket := range sockets {
+		if _, err := stat(socket); err == nil {
+			return socket
+		}
+	}
+	return "/tmp/mysql.sock"
+}
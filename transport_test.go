@@ -0,0 +1,66 @@
+package mylogin_test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestLoginResolveTransport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TCP is the default on Windows")
+	}
+
+	// Fake filesystem with only the Red Hat socket
+	stat := func(name string) (os.FileInfo, error) {
+		if name == "/var/lib/mysql/mysql.sock" {
+			return nil, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	noEnv := func(string) string { return "" }
+
+	for _, test := range []struct {
+		login    mylogin.Login
+		protocol string
+		getenv   func(string) string
+		expected string
+	}{
+		{mylogin.Login{}, "", noEnv, "unix(/var/lib/mysql/mysql.sock)/"},
+		{mylogin.Login{User: stringPtr("u"), Host: stringPtr("localhost"), Port: stringPtr("3307")}, "", noEnv, "u@unix(/var/lib/mysql/mysql.sock)/"},
+		{mylogin.Login{Host: stringPtr("localhost"), Socket: stringPtr("/s.sock")}, "", noEnv, "unix(/s.sock)/"},
+		{mylogin.Login{}, "", func(string) string { return "/env.sock" }, "unix(/env.sock)/"},
+		{mylogin.Login{Host: stringPtr("db"), Socket: stringPtr("/s.sock")}, "", noEnv, "tcp(db:3306)/"},
+		{mylogin.Login{}, "tcp", noEnv, "tcp(localhost:3306)/"},
+		{mylogin.Login{Host: stringPtr("localhost"), Port: stringPtr("3307"), Socket: stringPtr("/s.sock")}, "tcp", noEnv, "tcp(localhost:3307)/"},
+		{mylogin.Login{Host: stringPtr("db")}, "socket", noEnv, "unix(/var/lib/mysql/mysql.sock)/"},
+	} {
+		login, err := test.login.ResolveTransport(&mylogin.TransportOptions{
+			Protocol: test.protocol,
+			Stat:     stat,
+			Getenv:   test.getenv,
+		})
+		if err != nil {
+			t.Errorf("%s %q: %v", &test.login, test.protocol, err)
+			continue
+		}
+		if got := login.DSN(); got != test.expected {
+			t.Errorf("%s %q: got %s, expected %s", &test.login, test.protocol, got, test.expected)
+		}
+	}
+
+	login, err := (&mylogin.Login{}).ResolveTransport(&mylogin.TransportOptions{
+		Stat:    func(string) (os.FileInfo, error) { return nil, os.ErrNotExist },
+		Getenv:  noEnv,
+		Sockets: []string{"/a.sock"},
+	})
+	if err != nil || login.DSN() != "unix(/tmp/mysql.sock)/" {
+		t.Errorf("no socket found: got %v, %v", login, err)
+	}
+
+	if _, err = (&mylogin.Login{}).ResolveTransport(&mylogin.TransportOptions{Protocol: "pipe"}); err == nil {
+		t.Error("error expected")
+	}
+}
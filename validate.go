@@ -0,0 +1,155 @@
+package mylogin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Severity is the severity of an Issue.
+type Severity int
+
+// Severities, in increasing order.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = [...]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return "severity(" + strconv.Itoa(int(s)) + ")"
+	}
+	return severityNames[s]
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if string(text) == name {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Issue is a problem reported by Sections.Validate. Section is empty for
+// issues about the whole file.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Section  string   `json:"section"`
+	Option   string   `json:"option,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	b.WriteString(i.Severity.String())
+	b.WriteString(": ")
+	if i.Section != "" {
+		b.WriteByte('[')
+		b.WriteString(i.Section)
+		b.WriteByte(']')
+		if i.Option != "" {
+			b.WriteByte(' ')
+			b.WriteString(i.Option)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// Validate checks the sections for content that is invalid, ambiguous or
+// unsafe. Passwords are never included in the messages.
+//
+// Reported issues:
+//   - duplicate section names (see DuplicatePolicy and Normalize)
+//   - invalid section names
+//   - non-numeric or out of range ports
+//   - host and socket both set
+//   - socket paths that do not exist
+//   - empty passwords
+//   - root users
+func (sections Sections) Validate() []Issue {
+	var issues []Issue
+	seen := make(map[string]bool, len(sections))
+	for i := range sections {
+		s := &sections[i]
+		add := func(sev Severity, option string, format string, args ...interface{}) {
+			issues = append(issues, Issue{
+				Severity: sev,
+				Section:  s.Name,
+				Option:   option,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if seen[s.Name] {
+			add(SeverityWarning, "", "duplicate section")
+		}
+		seen[s.Name] = true
+
+		if err := checkSectionName(s.Name); err != nil {
+			add(SeverityError, "", "%v", err)
+		}
+
+		l := &s.Login
+		if l.Port != nil {
+			port, err := strconv.Atoi(*l.Port)
+			switch {
+			case err != nil:
+				add(SeverityError, "port", "%q is not a number", *l.Port)
+			case port < 1 || port > 65535:
+				add(SeverityError, "port", "%d is out of range", port)
+			}
+		}
+		if l.Host != nil && l.Socket != nil {
+			add(SeverityWarning, "socket", "both host and socket are set")
+		}
+		if l.Socket != nil {
+			if _, err := os.Stat(*l.Socket); os.IsNotExist(err) {
+				add(SeverityWarning, "socket", "%s doesn't exist", *l.Socket)
+			} else if err != nil {
+				add(SeverityWarning, "socket", "%v", err)
+			}
+		}
+		if l.Password != nil && *l.Password == "" {
+			add(SeverityWarning, "password", "empty password")
+		}
+		if l.User != nil && *l.User == "root" {
+			add(SeverityWarning, "user", "root user")
+		}
+	}
+	return issues
+}
+
+// checkSectionName checks that name can be used as a login path.
+func checkSectionName(name string) error {
+	if name == "" {
+		return errors.New("empty section name")
+	}
+	if strings.TrimSpace(name) != name {
+		return errors.New("section name has leading or trailing spaces")
+	}
+	for _, c := range name {
+		if c < ' ' || c == 0x7F || c == '[' || c == ']' {
+			return fmt.Errorf("invalid character %q in section name", c)
+		}
+	}
+	return nil
+}
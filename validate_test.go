@@ -0,0 +1,47 @@
+package mylogin_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+func TestSectionsValidate(t *testing.T) {
+	sections := mylogin.Sections{
+		{Name: "client", Login: mylogin.Login{User: stringPtr("dolmen"), Password: stringPtr("secret"), Port: stringPtr("3306")}},
+		{Name: "client", Login: mylogin.Login{User: stringPtr("root")}},
+		{Name: "bad]", Login: mylogin.Login{Port: stringPtr("http")}},
+		{Name: "remote", Login: mylogin.Login{Host: stringPtr("db"), Port: stringPtr("70000"), Password: stringPtr("")}},
+		{Name: "local", Login: mylogin.Login{Socket: stringPtr("/nonexistent/mysql.sock")}},
+	}
+	var got []string
+	for _, issue := range sections.Validate() {
+		got = append(got, issue.String())
+	}
+	expected := []string{
+		`warning: [client]: duplicate section`,
+		`warning: [client] user: root user`,
+		`error: [bad]]: invalid character ']' in section name`,
+		`error: [bad]] port: "http" is not a number`,
+		`error: [remote] port: 70000 is out of range`,
+		`warning: [remote] password: empty password`,
+		`warning: [local] socket: /nonexistent/mysql.sock doesn't exist`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got:\n%q\nexpected:\n%q", got, expected)
+	}
+
+	b, err := json.Marshal(mylogin.Issue{Severity: mylogin.SeverityError, Section: "s", Message: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"severity":"error","section":"s","message":"m"}` {
+		t.Errorf("JSON: %s", b)
+	}
+	var issue mylogin.Issue
+	if err = json.Unmarshal(b, &issue); err != nil || issue.Severity != mylogin.SeverityError {
+		t.Errorf("JSON: %+v %v", issue, err)
+	}
+}
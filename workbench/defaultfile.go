@@ -0,0 +1,10 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package workbench
+
+import "os"
+
+func platformDefaultFile() string {
+	return os.ExpandEnv(`${HOME}/.mysql/workbench/connections.xml`)
+}
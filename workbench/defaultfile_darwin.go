@@ -0,0 +1,7 @@
+package workbench
+
+import "os"
+
+func platformDefaultFile() string {
+	return os.ExpandEnv(`${HOME}/Library/Application Support/MySQL/Workbench/connections.xml`)
+}
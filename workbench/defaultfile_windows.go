@@ -0,0 +1,7 @@
+package workbench
+
+import "os"
+
+func platformDefaultFile() string {
+	return os.ExpandEnv(`${APPDATA}\MySQL\Workbench\connections.xml`)
+}
@@ -0,0 +1,216 @@
+// Package workbench imports the connections of MySQL Workbench
+// (connections.xml) as mylogin.cnf sections.
+//
+// Workbench keeps passwords in the keychain of the OS, so they are usually
+// missing from the imported sections. Connections through an SSH tunnel
+// can't be expressed in mylogin.cnf and are skipped.
+package workbench
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dolmen-go/mylogin"
+)
+
+// Drivers of Workbench connections.
+const (
+	DriverTCP    = "com.mysql.rdbms.mysql.driver.native"
+	DriverSocket = "com.mysql.rdbms.mysql.driver.native_socket" // Unix socket or Windows named pipe
+	DriverSSH    = "com.mysql.rdbms.mysql.driver.native_sshtun"
+)
+
+// Connection is a connection of Workbench (a db.mgmt.Connection object).
+type Connection struct {
+	Name   string
+	Driver string
+	// Params are the parameterValues: hostName, port, userName, socket,
+	// sshHost...
+	Params map[string]string
+}
+
+// DefaultFile returns the path of connections.xml:
+//
+//	Windows: %APPDATA%\MySQL\Workbench\connections.xml
+//	macOS: ~/Library/Application Support/MySQL/Workbench/connections.xml
+//	others: ~/.mysql/workbench/connections.xml
+func DefaultFile() string {
+	// see defaultfile.go, defaultfile_darwin.go, defaultfile_windows.go
+	return platformDefaultFile()
+}
+
+// value is a value of the GRT XML format of Workbench.
+type value struct {
+	Type       string  `xml:"type,attr"`
+	Key        string  `xml:"key,attr"`
+	StructName string  `xml:"struct-name,attr"`
+	Text       string  `xml:",chardata"`
+	Values     []value `xml:"value"`
+	Links      []value `xml:"link"`
+}
+
+// child returns the member of an object or dict with the given key.
+func (v *value) child(key string) *value {
+	for i := range v.Values {
+		if v.Values[i].Key == key {
+			return &v.Values[i]
+		}
+	}
+	for i := range v.Links {
+		if v.Links[i].Key == key {
+			return &v.Links[i]
+		}
+	}
+	return nil
+}
+
+func (v *value) text(key string) string {
+	if c := v.child(key); c != nil {
+		return c.Text
+	}
+	return ""
+}
+
+// Parse reads the connections from the content of connections.xml, in the
+// order of the file.
+func Parse(r io.Reader) ([]Connection, error) {
+	var doc struct {
+		XMLName xml.Name `xml:"data"`
+		Values  []value  `xml:"value"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("connections.xml: %v", err)
+	}
+	var conns []Connection
+	var walk func(values []value)
+	walk = func(values []value) {
+		for i := range values {
+			v := &values[i]
+			if v.Type == "object" && v.StructName == "db.mgmt.Connection" {
+				c := Connection{
+					Name:   v.text("name"),
+					Driver: v.text("driver"),
+					Params: make(map[string]string),
+				}
+				if params := v.child("parameterValues"); params != nil {
+					for _, p := range params.Values {
+						c.Params[p.Key] = p.Text
+					}
+				}
+				conns = append(conns, c)
+				continue
+			}
+			walk(v.Values)
+		}
+	}
+	walk(doc.Values)
+	return conns, nil
+}
+
+// ReadFile reads the connections from a connections.xml file.
+func ReadFile(filename string) ([]Connection, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// unstored are the parameters that change the connection but can't be
+// stored in mylogin.cnf, with their default value.
+var unstored = []struct {
+	name, defaultValue string
+}{
+	{"schema", ""},
+	{"SQL_MODE", ""},
+	{"useSSL", "1"}, // 0: no, 1: if available, 2: require...
+	{"sslCA", ""},
+	{"sslCert", ""},
+	{"sslKey", ""},
+	{"sslCipher", ""},
+	{"OPT_ENABLE_CLEARTEXT_PLUGIN", "0"},
+	{"CLIENT_COMPRESS", "0"},
+}
+
+// Import converts the connections to sections, named after the
+// connections. The issues report the connections that are skipped (as
+// errors), the parameters that are lost (as warnings) and the missing
+// passwords (as info).
+func Import(conns []Connection) (mylogin.Sections, []mylogin.Issue) {
+	var sections mylogin.Sections
+	var issues []mylogin.Issue
+	seen := make(map[string]bool, len(conns))
+	for _, c := range conns {
+		add := func(sev mylogin.Severity, option string, format string, args ...interface{}) {
+			issues = append(issues, mylogin.Issue{
+				Severity: sev,
+				Section:  c.Name,
+				Option:   option,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if seen[c.Name] {
+			add(mylogin.SeverityError, "", "duplicate connection name: skipped")
+			continue
+		}
+		seen[c.Name] = true
+
+		s := mylogin.Section{Name: c.Name}
+		l := &s.Login
+		param := func(name string) *string {
+			if v := c.Params[name]; v != "" {
+				return &v
+			}
+			return nil
+		}
+		switch c.Driver {
+		case DriverTCP:
+			l.Host = param("hostName")
+			l.Port = param("port")
+		case DriverSocket:
+			// The client uses the socket only with host localhost: any other
+			// host (even 127.0.0.1, or a host from [client]) means TCP.
+			// An empty socket is the default socket of the client.
+			localhost := "localhost"
+			l.Host = &localhost
+			l.Socket = param("socket")
+			if h := c.Params["hostName"]; h != "" && h != localhost {
+				add(mylogin.SeverityWarning, "hostName", "%q replaced by %q for a socket connection", h, localhost)
+			}
+		case DriverSSH:
+			add(mylogin.SeverityError, "", "SSH tunnel through %s: skipped", c.Params["sshHost"])
+			continue
+		default:
+			add(mylogin.SeverityError, "", "unsupported driver %q: skipped", c.Driver)
+			continue
+		}
+
+		// Check the name only, as Workbench allows any name
+		nameOK := true
+		for _, issue := range (mylogin.Sections{{Name: s.Name}}).Validate() {
+			if issue.Severity == mylogin.SeverityError && issue.Option == "" {
+				add(mylogin.SeverityError, "", "%s: skipped", issue.Message)
+				nameOK = false
+			}
+		}
+		if !nameOK {
+			continue
+		}
+
+		l.User = param("userName")
+		if l.Password = param("password"); l.Password == nil {
+			add(mylogin.SeverityInfo, "password", "no password (Workbench keeps it in the keychain)")
+		}
+		for _, p := range unstored {
+			if v, ok := c.Params[p.name]; ok && v != p.defaultValue {
+				add(mylogin.SeverityWarning, p.name, "%q can't be stored", v)
+			}
+		}
+		sections = append(sections, s)
+	}
+	return sections, issues
+}
@@ -0,0 +1,71 @@
+package workbench_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dolmen-go/mylogin"
+	"github.com/dolmen-go/mylogin/workbench"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestImport(t *testing.T) {
+	conns, err := workbench.ReadFile("testdata/connections.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 7 {
+		t.Fatalf("got %d connections, expected 7", len(conns))
+	}
+	if conns[1].Params["password"] != "s3cr&t" {
+		t.Errorf("password: got %q", conns[1].Params["password"])
+	}
+
+	sections, issues := workbench.Import(conns)
+	expected := mylogin.Sections{
+		{Name: "Local instance 3306", Login: mylogin.Login{User: stringPtr("root"), Host: stringPtr("127.0.0.1"), Port: stringPtr("3306")}},
+		{Name: "prod-ro", Login: mylogin.Login{User: stringPtr("app_ro"), Password: stringPtr("s3cr&t"), Host: stringPtr("db.example.com"), Port: stringPtr("3307")}},
+		{Name: "local socket", Login: mylogin.Login{User: stringPtr("dev"), Host: stringPtr("localhost"), Socket: stringPtr("/var/run/mysqld/mysqld.sock")}},
+		{Name: "socket with host", Login: mylogin.Login{User: stringPtr("test"), Host: stringPtr("localhost"), Socket: stringPtr("/tmp/mysql.sock")}},
+	}
+	if !reflect.DeepEqual(sections, expected) {
+		t.Errorf("got %#v, expected %#v", sections, expected)
+	}
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	expectedIssues := []string{
+		"info: [Local instance 3306] password: no password (Workbench keeps it in the keychain)",
+		`warning: [prod-ro] schema: "app" can't be stored`,
+		`warning: [prod-ro] useSSL: "3" can't be stored`,
+		`warning: [prod-ro] sslCA: "/etc/ssl/mysql-ca.pem" can't be stored`,
+		"info: [local socket] password: no password (Workbench keeps it in the keychain)",
+		`warning: [local socket] useSSL: "0" can't be stored`,
+		`warning: [socket with host] hostName: "127.0.0.1" replaced by "localhost" for a socket connection`,
+		"info: [socket with host] password: no password (Workbench keeps it in the keychain)",
+		"error: [prod-admin via bastion]: SSH tunnel through bastion.example.com: skipped",
+		"error: [prod-ro]: duplicate connection name: skipped",
+		`error: [[staging]]: invalid character '[' in section name: skipped`,
+	}
+	if !reflect.DeepEqual(got, expectedIssues) {
+		t.Errorf("issues:\ngot:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expectedIssues, "\n"))
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"", "<data>", "<connections/>"} {
+		if _, err := workbench.Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("%q: error expected", input)
+		}
+	}
+	conns, err := workbench.Parse(strings.NewReader(`<?xml version="1.0"?><data grt_format="2.0"/>`))
+	if err != nil || len(conns) != 0 {
+		t.Errorf("empty: got %v, %v", conns, err)
+	}
+}
@@ -2,9 +2,13 @@ package mylogin_test
 
 import (
 	"bytes"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -99,3 +103,95 @@ func TestReadWrite(t *testing.T) {
 		}()
 	}
 }
+
+func TestRekey(t *testing.T) {
+	orig, err := ioutil.ReadFile("testdata/padding05.cnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, ".mylogin.cnf")
+	if err = ioutil.WriteFile(filename, orig, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mylogin.Rekey(filename, rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := mylogin.Decode(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := mylogin.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Key() == before.Key() {
+		t.Error("key not changed")
+	}
+	if after.ByteOrder() != before.ByteOrder() {
+		t.Error("byte order changed")
+	}
+	plainBefore, _ := ioutil.ReadAll(before.PlainText())
+	plainAfter, _ := ioutil.ReadAll(after.PlainText())
+	if !bytes.Equal(plainBefore, plainAfter) {
+		t.Errorf("content changed: %q => %q", plainBefore, plainAfter)
+	}
+
+	if err = mylogin.Rekey(filename, errReader{errors.New("no entropy")}); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestWriteFileSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylogin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(filename, user string) mylogin.Key {
+		t.Helper()
+		key, err := mylogin.NewKey(rand.Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sections := mylogin.Sections{{Name: "client", Login: mylogin.Login{User: &user}}}
+		if err = mylogin.WriteFile(filename, mylogin.NewFile(key, nil, sections)); err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	target := filepath.Join(dir, "real.cnf")
+	write(target, "before")
+	link := filepath.Join(dir, "link.cnf")
+	if err = os.Symlink(target, link); err != nil {
+		t.Skip(err)
+	}
+	key := write(link, "after")
+
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s: not a symbolic link anymore (%v)", link, err)
+	}
+	file, err := mylogin.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Key() != key {
+		t.Error("target not written")
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}